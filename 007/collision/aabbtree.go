@@ -0,0 +1,370 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 動的AABB木(BVH)
+// 登録したAABBを余白(Margin)付きで保持して、少し動いたくらいでは木を組み替えない
+type AABBTree[T any] struct {
+	Margin float64 // AABBに付ける余白
+	nodes  []treeNode[T]
+	root   int
+	free   int // 未使用ノードのリスト(parentでつなぐ)
+}
+
+const nullNode = -1
+
+// 木のノード
+// 葉にだけアイテムが入る
+type treeNode[T any] struct {
+	bounds gmath.Rect
+	parent int
+	left   int
+	right  int
+	height int // 葉は0、未使用は-1
+	item   T
+}
+
+func (n *treeNode[T]) isLeaf() bool {
+	return n.left == nullNode
+}
+
+func NewAABBTree[T any](margin float64) *AABBTree[T] {
+	return &AABBTree[T]{
+		Margin: margin,
+		root:   nullNode,
+		free:   nullNode,
+	}
+}
+
+// アイテムを登録してIDを返す
+func (t *AABBTree[T]) Insert(item T, bounds gmath.Rect) int {
+	id := t.allocate()
+	t.nodes[id].bounds = t.fatten(bounds)
+	t.nodes[id].item = item
+	t.nodes[id].height = 0
+	t.insertLeaf(id)
+	return id
+}
+
+// アイテムを削除する
+func (t *AABBTree[T]) Remove(id int) {
+	t.removeLeaf(id)
+	t.release(id)
+}
+
+// アイテムのAABBを更新する
+// 余白の中に収まっている場合は何もしないでfalseを返す
+func (t *AABBTree[T]) Move(id int, bounds gmath.Rect) bool {
+	if containsRect(t.nodes[id].bounds, bounds) {
+		return false
+	}
+
+	t.removeLeaf(id)
+	t.nodes[id].bounds = t.fatten(bounds)
+	t.insertLeaf(id)
+	return true
+}
+
+// IDのアイテム
+func (t *AABBTree[T]) Item(id int) T {
+	return t.nodes[id].item
+}
+
+// 余白込みのAABB
+func (t *AABBTree[T]) FatBounds(id int) gmath.Rect {
+	return t.nodes[id].bounds
+}
+
+// 点を含むAABBのアイテムを列挙する
+// fがfalseを返すと打ち切る
+func (t *AABBTree[T]) QueryPoint(p gmath.Vec, f func(id int, item T) bool) {
+	t.query(func(b gmath.Rect) bool {
+		return b.Min.X <= p.X && p.X <= b.Max.X && b.Min.Y <= p.Y && p.Y <= b.Max.Y
+	}, f)
+}
+
+// 矩形と重なっているAABBのアイテムを列挙する
+func (t *AABBTree[T]) QueryRect(r gmath.Rect, f func(id int, item T) bool) {
+	t.query(func(b gmath.Rect) bool {
+		return overlapRect(b, r)
+	}, f)
+}
+
+// レイが通過するAABBのアイテムを列挙する
+func (t *AABBTree[T]) QueryRay(origin, dir gmath.Vec, maxDist float64, f func(id int, item T) bool) {
+	d := dir.Normalized()
+	t.query(func(b gmath.Rect) bool {
+		return rayRect(origin, d, maxDist, b)
+	}, f)
+}
+
+// 木の高さ
+func (t *AABBTree[T]) Height() int {
+	if t.root == nullNode {
+		return 0
+	}
+	return t.nodes[t.root].height
+}
+
+// 条件に当てはまるノードをたどる
+func (t *AABBTree[T]) query(test func(gmath.Rect) bool, f func(id int, item T) bool) {
+	if t.root == nullNode {
+		return
+	}
+
+	stack := []int{t.root}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		n := &t.nodes[id]
+		if !test(n.bounds) {
+			continue
+		}
+		if n.isLeaf() {
+			if !f(id, n.item) {
+				return
+			}
+			continue
+		}
+		stack = append(stack, n.left, n.right)
+	}
+}
+
+func (t *AABBTree[T]) fatten(b gmath.Rect) gmath.Rect {
+	m := gmath.Vec{X: t.Margin, Y: t.Margin}
+	return gmath.Rect{Min: b.Min.Sub(m), Max: b.Max.Add(m)}
+}
+
+// ノードを確保する
+func (t *AABBTree[T]) allocate() int {
+	if t.free == nullNode {
+		t.nodes = append(t.nodes, treeNode[T]{})
+		t.free = len(t.nodes) - 1
+		t.nodes[t.free].parent = nullNode
+	}
+
+	id := t.free
+	t.free = t.nodes[id].parent
+	t.nodes[id] = treeNode[T]{parent: nullNode, left: nullNode, right: nullNode}
+	return id
+}
+
+// ノードを解放する
+func (t *AABBTree[T]) release(id int) {
+	t.nodes[id] = treeNode[T]{parent: t.free, left: nullNode, right: nullNode, height: -1}
+	t.free = id
+}
+
+// 葉を木に挿入する
+// 周長が一番増えない場所を探して兄弟にする
+func (t *AABBTree[T]) insertLeaf(leaf int) {
+	if t.root == nullNode {
+		t.root = leaf
+		t.nodes[leaf].parent = nullNode
+		return
+	}
+
+	lb := t.nodes[leaf].bounds
+	index := t.root
+	for !t.nodes[index].isLeaf() {
+		n := t.nodes[index]
+		area := perimeter(n.bounds)
+		combined := perimeter(unionRect(n.bounds, lb))
+
+		// ここに新しい親を作るコスト
+		cost := 2 * combined
+
+		// 子に降りる場合に親が広がるコスト
+		inheritance := 2 * (combined - area)
+
+		childCost := func(c int) float64 {
+			u := perimeter(unionRect(lb, t.nodes[c].bounds))
+			if t.nodes[c].isLeaf() {
+				return u + inheritance
+			}
+			return u - perimeter(t.nodes[c].bounds) + inheritance
+		}
+		cost1 := childCost(n.left)
+		cost2 := childCost(n.right)
+
+		if cost < cost1 && cost < cost2 {
+			break
+		}
+		if cost1 < cost2 {
+			index = n.left
+		} else {
+			index = n.right
+		}
+	}
+
+	// 兄弟と新しい親を作る
+	sibling := index
+	oldParent := t.nodes[sibling].parent
+	newParent := t.allocate()
+	t.nodes[newParent].parent = oldParent
+	t.nodes[newParent].bounds = unionRect(lb, t.nodes[sibling].bounds)
+	t.nodes[newParent].height = t.nodes[sibling].height + 1
+	t.nodes[newParent].left = sibling
+	t.nodes[newParent].right = leaf
+	t.nodes[sibling].parent = newParent
+	t.nodes[leaf].parent = newParent
+
+	if oldParent == nullNode {
+		t.root = newParent
+	} else if t.nodes[oldParent].left == sibling {
+		t.nodes[oldParent].left = newParent
+	} else {
+		t.nodes[oldParent].right = newParent
+	}
+
+	t.refit(t.nodes[leaf].parent)
+}
+
+// 葉を木から外す
+func (t *AABBTree[T]) removeLeaf(leaf int) {
+	if leaf == t.root {
+		t.root = nullNode
+		return
+	}
+
+	parent := t.nodes[leaf].parent
+	grandParent := t.nodes[parent].parent
+	sibling := t.nodes[parent].left
+	if sibling == leaf {
+		sibling = t.nodes[parent].right
+	}
+
+	if grandParent == nullNode {
+		t.root = sibling
+		t.nodes[sibling].parent = nullNode
+		t.release(parent)
+		return
+	}
+
+	// 親を消して兄弟を祖父につなげる
+	if t.nodes[grandParent].left == parent {
+		t.nodes[grandParent].left = sibling
+	} else {
+		t.nodes[grandParent].right = sibling
+	}
+	t.nodes[sibling].parent = grandParent
+	t.release(parent)
+
+	t.refit(grandParent)
+}
+
+// 根まで遡りながら回転でバランスを取り、AABBと高さを更新する
+func (t *AABBTree[T]) refit(index int) {
+	for index != nullNode {
+		index = t.balance(index)
+
+		l := t.nodes[index].left
+		r := t.nodes[index].right
+		t.nodes[index].height = 1 + max(t.nodes[l].height, t.nodes[r].height)
+		t.nodes[index].bounds = unionRect(t.nodes[l].bounds, t.nodes[r].bounds)
+
+		index = t.nodes[index].parent
+	}
+}
+
+// 左右の高さの差が2以上ある場合に回転させる
+// 回転後にaの位置に来たノードを返す
+func (t *AABBTree[T]) balance(a int) int {
+	if t.nodes[a].isLeaf() || t.nodes[a].height < 2 {
+		return a
+	}
+
+	b := t.nodes[a].left
+	c := t.nodes[a].right
+	diff := t.nodes[c].height - t.nodes[b].height
+
+	if diff > 1 {
+		t.rotate(a, c, false)
+		return c
+	}
+	if diff < -1 {
+		t.rotate(a, b, true)
+		return b
+	}
+
+	return a
+}
+
+// 子ノードupをaの位置に持ち上げる
+// upLeftはupがaの左の子かどうか
+func (t *AABBTree[T]) rotate(a, up int, upLeft bool) {
+	other := t.nodes[a].right // aに残る子
+	if !upLeft {
+		other = t.nodes[a].left
+	}
+	f := t.nodes[up].left
+	g := t.nodes[up].right
+
+	// upをaの親の位置に付け替える
+	t.nodes[up].left = a
+	t.nodes[up].parent = t.nodes[a].parent
+	t.nodes[a].parent = up
+	if p := t.nodes[up].parent; p == nullNode {
+		t.root = up
+	} else if t.nodes[p].left == a {
+		t.nodes[p].left = up
+	} else {
+		t.nodes[p].right = up
+	}
+
+	// upの子のうち高いほうをupに残し、低いほうをaに渡す
+	keep, give := f, g
+	if t.nodes[f].height <= t.nodes[g].height {
+		keep, give = g, f
+	}
+	t.nodes[up].right = keep
+	if upLeft {
+		t.nodes[a].left = give
+	} else {
+		t.nodes[a].right = give
+	}
+	t.nodes[give].parent = a
+
+	t.nodes[a].bounds = unionRect(t.nodes[other].bounds, t.nodes[give].bounds)
+	t.nodes[a].height = 1 + max(t.nodes[other].height, t.nodes[give].height)
+	t.nodes[up].bounds = unionRect(t.nodes[a].bounds, t.nodes[keep].bounds)
+	t.nodes[up].height = 1 + max(t.nodes[a].height, t.nodes[keep].height)
+}
+
+// 矩形の周長
+func perimeter(r gmath.Rect) float64 {
+	return 2 * (r.Width() + r.Height())
+}
+
+// aがbを含んでいるか
+func containsRect(a, b gmath.Rect) bool {
+	return a.Min.X <= b.Min.X && a.Min.Y <= b.Min.Y && b.Max.X <= a.Max.X && b.Max.Y <= a.Max.Y
+}
+
+// レイと矩形の判定(スラブ法)
+// dは正規化済みの方向
+func rayRect(origin, d gmath.Vec, maxDist float64, r gmath.Rect) bool {
+	tmin := 0.0
+	tmax := maxDist
+
+	slab := func(o, d, lo, hi float64) bool {
+		if d == 0 {
+			return lo <= o && o <= hi
+		}
+		t1 := (lo - o) / d
+		t2 := (hi - o) / d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tmin = math.Max(tmin, t1)
+		tmax = math.Min(tmax, t2)
+		return tmin <= tmax
+	}
+
+	return slab(origin.X, d.X, r.Min.X, r.Max.X) && slab(origin.Y, d.Y, r.Min.Y, r.Max.Y)
+}
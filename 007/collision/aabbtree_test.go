@@ -0,0 +1,144 @@
+package collision
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+func randomRect(r *rand.Rand) gmath.Rect {
+	p := gmath.Vec{X: r.Float64() * 1000, Y: r.Float64() * 1000}
+	return gmath.Rect{Min: p, Max: p.Add(gmath.Vec{X: 1 + r.Float64()*50, Y: 1 + r.Float64()*50})}
+}
+
+// 親のAABBが子を含み、高さと親子関係が正しく、左右の高さの差が1以下か
+func checkTree[T any](t *testing.T, tree *AABBTree[T], id int) int {
+	t.Helper()
+	n := &tree.nodes[id]
+	if n.isLeaf() {
+		if n.height != 0 {
+			t.Fatalf("leaf %d has height %d", id, n.height)
+		}
+		return 1
+	}
+
+	l, r := &tree.nodes[n.left], &tree.nodes[n.right]
+	if l.parent != id || r.parent != id {
+		t.Fatalf("node %d children have wrong parents", id)
+	}
+	if !containsRect(n.bounds, l.bounds) || !containsRect(n.bounds, r.bounds) {
+		t.Fatalf("node %d does not contain its children", id)
+	}
+	if n.height != 1+max(l.height, r.height) {
+		t.Fatalf("node %d has height %d", id, n.height)
+	}
+	if d := l.height - r.height; d > 1 || d < -1 {
+		t.Fatalf("node %d is unbalanced (%d, %d)", id, l.height, r.height)
+	}
+	return checkTree(t, tree, n.left) + checkTree(t, tree, n.right)
+}
+
+// ランダムに登録・移動・削除して、問い合わせ結果を総当たりと比べる
+func TestAABBTreeRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tree := NewAABBTree[int](2)
+	bounds := map[int]gmath.Rect{} // IDごとの実際のAABB
+
+	for step := 0; step < 3000; step++ {
+		var ids []int
+		for id := range bounds {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+
+		switch op := r.Intn(10); {
+		case op < 5 || len(ids) == 0:
+			b := randomRect(r)
+			id := tree.Insert(step, b)
+			if _, found := bounds[id]; found {
+				t.Fatalf("id %d reused while alive", id)
+			}
+			bounds[id] = b
+		case op < 8:
+			id := ids[r.Intn(len(ids))]
+			b := bounds[id]
+			d := gmath.Vec{X: r.Float64()*20 - 10, Y: r.Float64()*20 - 10}
+			b = gmath.Rect{Min: b.Min.Add(d), Max: b.Max.Add(d)}
+			tree.Move(id, b)
+			bounds[id] = b
+		default:
+			id := ids[r.Intn(len(ids))]
+			tree.Remove(id)
+			delete(bounds, id)
+		}
+
+		if len(bounds) > 0 {
+			if n := checkTree(t, tree, tree.root); n != len(bounds) {
+				t.Fatalf("step %d: tree has %d leaves, want %d", step, n, len(bounds))
+			}
+		}
+
+		// 余白込みのAABBは実際のAABBを含む
+		for id, b := range bounds {
+			if !containsRect(tree.FatBounds(id), b) {
+				t.Fatalf("step %d: fat bounds of %d do not contain its bounds", step, id)
+			}
+		}
+
+		q := randomRect(r)
+		var got []int
+		tree.QueryRect(q, func(id int, item int) bool {
+			got = append(got, id)
+			return true
+		})
+		slices.Sort(got)
+		var want []int
+		for id := range bounds {
+			if overlapRect(tree.FatBounds(id), q) {
+				want = append(want, id)
+			}
+		}
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("step %d: QueryRect = %v, want %v", step, got, want)
+		}
+
+		p := q.Min
+		got = got[:0]
+		tree.QueryPoint(p, func(id int, item int) bool {
+			got = append(got, id)
+			return true
+		})
+		slices.Sort(got)
+		want = want[:0]
+		for id := range bounds {
+			if b := tree.FatBounds(id); b.Min.X <= p.X && p.X <= b.Max.X && b.Min.Y <= p.Y && p.Y <= b.Max.Y {
+				want = append(want, id)
+			}
+		}
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("step %d: QueryPoint = %v, want %v", step, got, want)
+		}
+
+		dir := gmath.Vec{X: r.Float64() - 0.5, Y: r.Float64() - 0.5}
+		got = got[:0]
+		tree.QueryRay(p, dir, 300, func(id int, item int) bool {
+			got = append(got, id)
+			return true
+		})
+		slices.Sort(got)
+		want = want[:0]
+		for id := range bounds {
+			if rayRect(p, dir.Normalized(), 300, tree.FatBounds(id)) {
+				want = append(want, id)
+			}
+		}
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("step %d: QueryRay = %v, want %v", step, got, want)
+		}
+	}
+}
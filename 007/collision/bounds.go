@@ -0,0 +1,80 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// AABB(軸に平行な外接矩形)を返せる衝突判定範囲のインターフェース
+type Bounder interface {
+	Bounds() gmath.Rect
+}
+
+// グローバル座標でのAABB
+func (p *Polygon) Bounds() gmath.Rect {
+	return p.updateCache().bounds
+}
+
+func (c *Circle) Bounds() gmath.Rect {
+	if s := scaledShape(c); s != Tester(c) {
+		return s.(Bounder).Bounds()
+	}
+	d := gmath.Vec{X: c.Radius, Y: c.Radius}
+	return gmath.Rect{Min: c.Pos.Sub(d), Max: c.Pos.Add(d)}
+}
+
+// Or/Xorの場合は子要素の和、Andの場合は子要素の共通部分、Notの場合は最初の子要素
+func (c *Composit) Bounds() gmath.Rect {
+	var result gmath.Rect
+	first := true
+	for _, d := range c.Collisions {
+		if c.Operator == CompositNot && !first {
+			break
+		}
+
+		b, ok := boundsOf(d)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case first:
+			result = b
+			first = false
+		case c.Operator == CompositAnd:
+			result = intersectRect(result, b)
+		default:
+			result = unionRect(result, b)
+		}
+	}
+
+	return result
+}
+
+// 点を含むように矩形を広げる
+func extendRect(r gmath.Rect, v gmath.Vec) gmath.Rect {
+	return gmath.Rect{
+		Min: gmath.Vec{X: math.Min(r.Min.X, v.X), Y: math.Min(r.Min.Y, v.Y)},
+		Max: gmath.Vec{X: math.Max(r.Max.X, v.X), Y: math.Max(r.Max.Y, v.Y)},
+	}
+}
+
+// 2つの矩形を含む矩形
+func unionRect(a, b gmath.Rect) gmath.Rect {
+	return extendRect(extendRect(a, b.Min), b.Max)
+}
+
+// 2つの矩形の共通部分
+// 重なっていない場合はMinがMaxを超える
+func intersectRect(a, b gmath.Rect) gmath.Rect {
+	return gmath.Rect{
+		Min: gmath.Vec{X: math.Max(a.Min.X, b.Min.X), Y: math.Max(a.Min.Y, b.Min.Y)},
+		Max: gmath.Vec{X: math.Min(a.Max.X, b.Max.X), Y: math.Min(a.Max.Y, b.Max.Y)},
+	}
+}
+
+// 矩形同士が重なっているか(境界で接している場合も含む)
+func overlapRect(a, b gmath.Rect) bool {
+	return a.Min.X <= b.Max.X && b.Min.X <= a.Max.X && a.Min.Y <= b.Max.Y && b.Min.Y <= a.Max.Y
+}
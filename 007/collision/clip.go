@@ -0,0 +1,369 @@
+package collision
+
+import (
+	"math"
+	"slices"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 多角形の演算の種類
+type ClipOperation int
+
+const (
+	ClipUnion        ClipOperation = 0 // 和
+	ClipIntersection ClipOperation = 1 // 共通部分
+	ClipDifference   ClipOperation = 2 // 差(aからbを取り除いた部分)
+	ClipXor          ClipOperation = 3 // どちらか一方だけに含まれる部分
+)
+
+// これより近い点は同じ点とみなす
+const clipEpsilon = 1e-7
+
+// 演算のためにエッジを分割した線分
+type clipEdge struct {
+	from gmath.Vec
+	to   gmath.Vec
+}
+
+// エッジ上の分割点
+type clipSplit struct {
+	t float64   // エッジ上の位置(始点0、終点1)
+	p gmath.Vec // 座標
+}
+
+// 相手の領域に対するエッジの位置
+type edgeSide int
+
+const (
+	edgeOutside  edgeSide = iota // 外側
+	edgeInside                   // 内側
+	edgeShared                   // 相手のエッジと同じ向きで重なっている
+	edgeOpposite                 // 相手のエッジと逆向きで重なっている
+)
+
+// 単純多角形同士の演算
+// 頂点集合は右回りでも左回りでもよい。結果は外形が右回り、穴が左回りの輪郭の集合になる
+func Clip(a, b []gmath.Vec, op ClipOperation) ([][]gmath.Vec, error) {
+	var regions [2][][]gmath.Vec
+	for i, vs := range [][]gmath.Vec{a, b} {
+		if len(vs) < 3 {
+			return nil, ErrTooFewVertices
+		}
+		if selfIntersecting(vs) {
+			return nil, ErrSelfIntersecting
+		}
+		vs = slices.Clone(vs)
+		if polygonArea(vs) < 0 {
+			slices.Reverse(vs)
+		}
+		regions[i] = [][]gmath.Vec{vs}
+	}
+	return ClipRegions(regions[0], regions[1], op), nil
+}
+
+// 輪郭の集合で表した領域同士の演算
+// 外形は右回り、穴は左回りで表す。Clipの結果をそのまま渡して演算を重ねられる
+// 互いのエッジを交点で分割し、相手の内側か外側かで残すエッジを選んでつなぎ直す
+func ClipRegions(a, b [][]gmath.Vec, op ClipOperation) [][]gmath.Vec {
+	if op == ClipXor {
+		return append(ClipRegions(a, b, ClipDifference), ClipRegions(b, a, ClipDifference)...)
+	}
+
+	var edges []clipEdge
+	for _, e := range splitEdges(a, b) {
+		switch classifyEdge(e, b) {
+		case edgeOutside:
+			if op == ClipUnion || op == ClipDifference {
+				edges = append(edges, e)
+			}
+		case edgeInside:
+			if op == ClipIntersection {
+				edges = append(edges, e)
+			}
+		case edgeShared:
+			// 同じ側が内側なので、和と共通部分の境界になる
+			if op == ClipUnion || op == ClipIntersection {
+				edges = append(edges, e)
+			}
+		case edgeOpposite:
+			// 両側が埋まるか両側が空くので、差の場合だけ境界になる
+			if op == ClipDifference {
+				edges = append(edges, e)
+			}
+		}
+	}
+
+	// 重なっているエッジはaの方だけ使う
+	for _, e := range splitEdges(b, a) {
+		switch classifyEdge(e, a) {
+		case edgeOutside:
+			if op == ClipUnion {
+				edges = append(edges, e)
+			}
+		case edgeInside:
+			switch op {
+			case ClipIntersection:
+				edges = append(edges, e)
+			case ClipDifference:
+				// 取り除いた部分の境界なので向きが逆になる
+				edges = append(edges, clipEdge{from: e.to, to: e.from})
+			}
+		}
+	}
+
+	return linkEdges(edges)
+}
+
+// aの各エッジを、bのエッジとの交点や重なりの端で分割する
+func splitEdges(a, b [][]gmath.Vec) []clipEdge {
+	var result []clipEdge
+	for _, loop := range a {
+		for i := range loop {
+			p1 := loop[i]
+			p2 := loop[(i+1)%len(loop)]
+
+			splits := []clipSplit{{0, p1}, {1, p2}}
+			for _, other := range b {
+				for j := range other {
+					splits = appendSplits(splits, p1, p2, other[j], other[(j+1)%len(other)])
+				}
+			}
+			slices.SortStableFunc(splits, func(s1, s2 clipSplit) int {
+				return cmpFloat(s1.t, s2.t)
+			})
+
+			// 近すぎる分割点はまとめる。端点は隣のエッジと共有しているので残す
+			pts := make([]gmath.Vec, 0, len(splits))
+			for _, s := range splits {
+				if len(pts) > 0 && pts[len(pts)-1].DistanceSquaredTo(s.p) < clipEpsilon*clipEpsilon {
+					if s.t == 1 {
+						pts[len(pts)-1] = s.p
+					}
+					continue
+				}
+				pts = append(pts, s.p)
+			}
+			for k := 0; k < len(pts)-1; k++ {
+				result = append(result, clipEdge{from: pts[k], to: pts[k+1]})
+			}
+		}
+	}
+	return result
+}
+
+// エッジp1→p2とエッジq1→q2の交点を分割点に追加する
+// 端点で接している場合は、もう一方の端点の座標をそのまま使う
+func appendSplits(splits []clipSplit, p1, p2, q1, q2 gmath.Vec) []clipSplit {
+	const tol = 1e-9
+	r := p2.Sub(p1)
+	s := q2.Sub(q1)
+	qp := q1.Sub(p1)
+	d := r.X*s.Y - r.Y*s.X
+
+	if math.Abs(d) > tol*r.Len()*s.Len() {
+		t := (qp.X*s.Y - qp.Y*s.X) / d
+		u := (qp.X*r.Y - qp.Y*r.X) / d
+		if t <= tol || t >= 1-tol || u < -tol || u > 1+tol {
+			return splits
+		}
+
+		p := p1.Add(r.Mulf(t))
+		switch {
+		case u <= tol:
+			p = q1
+		case u >= 1-tol:
+			p = q2
+		}
+		return append(splits, clipSplit{t, p})
+	}
+
+	// 平行で同じ直線上にある場合は、重なりの端で分割する
+	l := r.LenSquared()
+	if l == 0 || math.Abs(qp.X*r.Y-qp.Y*r.X) > clipEpsilon*math.Sqrt(l) {
+		return splits
+	}
+	for _, q := range []gmath.Vec{q1, q2} {
+		if t := q.Sub(p1).Dot(r) / l; t > tol && t < 1-tol {
+			splits = append(splits, clipSplit{t, q})
+		}
+	}
+	return splits
+}
+
+// 分割したエッジが領域の内側か外側か
+// 交点で分割してあるので、中点だけ調べればよい
+func classifyEdge(e clipEdge, region [][]gmath.Vec) edgeSide {
+	m := e.from.Add(e.to).Mulf(0.5)
+	dir := e.to.Sub(e.from)
+	for _, loop := range region {
+		for i := range loop {
+			q1 := loop[i]
+			q2 := loop[(i+1)%len(loop)]
+			if closestPointOnSegment(m, q1, q2).DistanceSquaredTo(m) >= clipEpsilon*clipEpsilon {
+				continue
+			}
+			if dir.Dot(q2.Sub(q1)) > 0 {
+				return edgeShared
+			}
+			return edgeOpposite
+		}
+	}
+
+	if windingNumber(m, region) != 0 {
+		return edgeInside
+	}
+	return edgeOutside
+}
+
+// 点のまわりを輪郭が何周しているか
+// 右回りの輪郭の内側は1、左回りの輪郭の内側は-1になる
+func windingNumber(p gmath.Vec, region [][]gmath.Vec) int {
+	n := 0
+	for _, loop := range region {
+		for i := range loop {
+			a := loop[i]
+			b := loop[(i+1)%len(loop)]
+			side := (b.X-a.X)*(p.Y-a.Y) - (p.X-a.X)*(b.Y-a.Y)
+			if a.Y <= p.Y && b.Y > p.Y && side > 0 {
+				n++
+			} else if a.Y > p.Y && b.Y <= p.Y && side < 0 {
+				n--
+			}
+		}
+	}
+	return n
+}
+
+// 向きのあるエッジをつないで輪郭にする
+// 分岐する場所では、内側に一番きつく曲がるエッジを選んで輪郭を小さく分ける
+func linkEdges(edges []clipEdge) [][]gmath.Vec {
+	used := make([]bool, len(edges))
+	var result [][]gmath.Vec
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+
+		var loop []gmath.Vec
+		for cur := i; cur >= 0; cur = nextEdge(edges, used, cur) {
+			used[cur] = true
+			loop = append(loop, edges[cur].from)
+		}
+
+		loop = cleanOutline(loop)
+		if len(loop) >= 3 && math.Abs(polygonArea(loop)) > pieceEpsilon {
+			result = append(result, loop)
+		}
+	}
+	return result
+}
+
+// curの終点から続く未使用のエッジ
+// 来た方向から時計回りに見て最初のエッジを選ぶ。無ければ-1を返す
+func nextEdge(edges []clipEdge, used []bool, cur int) int {
+	e := edges[cur]
+	back := e.from.Sub(e.to)
+	ba := math.Atan2(back.Y, back.X)
+
+	result := -1
+	best := math.Inf(1)
+	for i, f := range edges {
+		if used[i] || f.from.DistanceSquaredTo(e.to) >= clipEpsilon*clipEpsilon {
+			continue
+		}
+		d := f.to.Sub(f.from)
+		a := math.Mod(ba-math.Atan2(d.Y, d.X)+4*math.Pi, 2*math.Pi)
+		if a == 0 {
+			a = 2 * math.Pi
+		}
+		if a < best {
+			best = a
+			result = i
+		}
+	}
+	return result
+}
+
+// 重なった頂点と一直線上の頂点を取り除く
+func cleanOutline(vs []gmath.Vec) []gmath.Vec {
+	for changed := true; changed && len(vs) >= 3; {
+		changed = false
+		for i := 0; i < len(vs) && len(vs) >= 3; i++ {
+			a := vs[(i+len(vs)-1)%len(vs)]
+			b := vs[i]
+			c := vs[(i+1)%len(vs)]
+			e1 := b.Sub(a)
+			e2 := c.Sub(b)
+			if e1.LenSquared() < clipEpsilon*clipEpsilon || math.Abs(turn(a, b, c)) <= clipEpsilon*e1.Len()*e2.Len() {
+				vs = slices.Delete(vs, i, i+1)
+				changed = true
+				i--
+			}
+		}
+	}
+	return vs
+}
+
+// 輪郭の集合から複合形状を作る
+// 右回りの輪郭を外形、左回りの輪郭を穴として、凸型多角形に分解してまとめる
+// 穴のある外形はNotの複合形状になる
+func NewRegion(outlines [][]gmath.Vec) (*Composit, error) {
+	var outers [][]gmath.Vec
+	var holes [][]gmath.Vec
+	for _, vs := range outlines {
+		if polygonArea(vs) > 0 {
+			outers = append(outers, vs)
+		} else {
+			holes = append(holes, vs)
+		}
+	}
+
+	// 穴は、それを含む一番小さい外形に入れる
+	owned := make([][][]gmath.Vec, len(outers))
+	for _, h := range holes {
+		idx := -1
+		for i, o := range outers {
+			if windingNumber(holeSample(h), [][]gmath.Vec{o}) == 0 {
+				continue
+			}
+			if idx < 0 || polygonArea(o) < polygonArea(outers[idx]) {
+				idx = i
+			}
+		}
+		if idx >= 0 {
+			owned[idx] = append(owned[idx], h)
+		}
+	}
+
+	result := &Composit{Operator: CompositOr}
+	for i, o := range outers {
+		c, err := NewConcave(o)
+		if err != nil {
+			return nil, err
+		}
+		if len(owned[i]) == 0 {
+			result.Collisions = append(result.Collisions, c)
+			continue
+		}
+
+		n := &Composit{Operator: CompositNot, Collisions: []Tester{c}}
+		for _, h := range owned[i] {
+			hc, err := NewConcave(h)
+			if err != nil {
+				return nil, err
+			}
+			n.Collisions = append(n.Collisions, hc)
+		}
+		result.Collisions = append(result.Collisions, n)
+	}
+	return result, nil
+}
+
+// 穴の内側にある点
+// 穴の頂点は外形の辺上にある場合があるので、最初のエッジの中点を少し穴の内側にずらす
+func holeSample(h []gmath.Vec) gmath.Vec {
+	e := h[1].Sub(h[0])
+	n := gmath.Vec{X: e.Y, Y: -e.X}.Normalized()
+	return h[0].Add(e.Mulf(0.5)).Add(n.Mulf(clipEpsilon * 10))
+}
@@ -0,0 +1,101 @@
+package collision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+func square(x, y, size float64) []gmath.Vec {
+	return []gmath.Vec{{X: x, Y: y}, {X: x + size, Y: y}, {X: x + size, Y: y + size}, {X: x, Y: y + size}}
+}
+
+// 輪郭の集合の面積(穴は左回りなので引かれる)
+func regionArea(outlines [][]gmath.Vec) float64 {
+	a := 0.0
+	for _, o := range outlines {
+		a += polygonArea(o)
+	}
+	return a
+}
+
+func TestClipArea(t *testing.T) {
+	// 正方形は星の中心の五角形に含まれる
+	star := starVertices(5, 50, 20)
+	s := polygonArea(star)
+
+	tests := []struct {
+		name string
+		a, b []gmath.Vec
+		want [4]float64 // Union, Intersection, Difference, Xor
+	}{
+		{"overlapping", square(0, 0, 10), square(5, 5, 10), [4]float64{175, 25, 75, 150}},
+		{"shared edge", square(0, 0, 10), square(10, 0, 10), [4]float64{200, 0, 100, 200}},
+		{"identical", square(0, 0, 10), square(0, 0, 10), [4]float64{100, 100, 0, 0}},
+		{"disjoint", square(0, 0, 10), square(20, 0, 10), [4]float64{200, 0, 100, 200}},
+		{"contained", square(0, 0, 30), square(10, 10, 10), [4]float64{900, 100, 800, 800}},
+		{"left-wound", reversed(square(0, 0, 10)), square(5, 0, 10), [4]float64{150, 50, 50, 100}},
+		{"star", star, square(-10, -10, 20), [4]float64{s, 400, s - 400, s - 400}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for op := ClipUnion; op <= ClipXor; op++ {
+				outlines, err := Clip(tt.a, tt.b, op)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := regionArea(outlines); math.Abs(got-tt.want[op]) > 1e-6 {
+					t.Errorf("op %d: area = %v, want %v", op, got, tt.want[op])
+				}
+			}
+		})
+	}
+}
+
+// 辺を共有する正方形の和は1つの長方形になる
+func TestClipUnionMergesSharedEdge(t *testing.T) {
+	outlines, err := Clip(square(0, 0, 10), square(10, 0, 10), ClipUnion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outlines) != 1 || len(outlines[0]) != 4 {
+		t.Errorf("union = %v, want one rectangle", outlines)
+	}
+}
+
+// 穴のある領域を作り、演算を重ねても穴が残る
+func TestClipHole(t *testing.T) {
+	outlines, err := Clip(square(0, 0, 30), square(10, 10, 10), ClipDifference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outlines) != 2 {
+		t.Fatalf("difference = %v, want an outline and a hole", outlines)
+	}
+
+	region, err := NewRegion(outlines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		p    gmath.Vec
+		want bool
+	}{
+		{gmath.Vec{X: 5, Y: 5}, true},
+		{gmath.Vec{X: 15, Y: 15}, false},
+		{gmath.Vec{X: 25, Y: 15}, true},
+		{gmath.Vec{X: 35, Y: 15}, false},
+	} {
+		if got := TestPoint(c.p.X, c.p.Y, region); got != c.want {
+			t.Errorf("TestPoint(%v) = %v, want %v", c.p, got, c.want)
+		}
+	}
+
+	// 穴をまたぐ帯との共通部分は穴の分だけ欠ける
+	band := [][]gmath.Vec{{{X: -5, Y: 12}, {X: 35, Y: 12}, {X: 35, Y: 18}, {X: -5, Y: 18}}}
+	if got := regionArea(ClipRegions(outlines, band, ClipIntersection)); math.Abs(got-120) > 1e-6 {
+		t.Errorf("hole intersection area = %v, want 120", got)
+	}
+}
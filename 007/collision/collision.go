@@ -1,6 +1,7 @@
 package collision
 
 import (
+	"math"
 	"slices"
 
 	"github.com/quasilyte/gmath"
@@ -17,74 +18,63 @@ type Polygon struct {
 	Rad      gmath.Rad   // 回転角度(ラジアン)
 	Vertices []gmath.Vec // 右周りの頂点集合
 	Origin   gmath.Vec   // 頂点集合の回転原点
+	Scale    gmath.Vec   // 回転原点を基準にした拡大率。ゼロ値の成分は等倍
+
+	cache polygonCache // グローバル座標に変換した結果
 }
 
-func (p *Polygon) Test(o Tester) bool {
-	result := false
-	switch v := o.(type) {
-	case *Polygon:
-		result = TestPolygonPolygon(v, p)
-	case *Circle:
-		result = TestCirclePolygon(v, p)
-	case *Composit:
-		result = TestPolygonComposit(p, v)
-	default:
-		result = false
-	}
+// 変換した頂点集合などのキャッシュ
+// Pos/Rad/Origin/Verticesが変わったときだけ計算し直す
+type polygonCache struct {
+	owner    *Polygon    // 値ごとコピーされた多角形とバッファを共有しないための持ち主
+	pos      gmath.Vec   // 計算したときの座標
+	rad      gmath.Rad   // 計算したときの回転角度
+	origin   gmath.Vec   // 計算したときの回転原点
+	scale    gmath.Vec   // 計算したときの拡大率
+	vertices []gmath.Vec // 計算したときの頂点集合(差し替えの検出用)
+	world    []gmath.Vec // グローバル座標の頂点集合(末尾に1個目の頂点を追加)
+	axes     []gmath.Vec // 正規化したエッジのベクトル
+	bounds   gmath.Rect  // AABB
+}
 
-	return result
+func (p *Polygon) Test(o Tester) bool {
+	return TestShapes(p, o)
 }
 
 // 円
+// 拡大率のXとYが違う場合は楕円として判定する。拡大率はTestShapesなどの入口で反映するので、
+// TestCircleCircleのような組み合わせごとの関数に直接渡す場合は等倍でなければならない
 type Circle struct {
 	Pos    gmath.Vec // 中心座標
 	Radius float64   // 半径
+	Scale  gmath.Vec // 拡大率。ゼロ値の成分は等倍
+	Rad    gmath.Rad // 回転角度(ラジアン)。楕円になる場合だけ使う
 }
 
 func (c *Circle) Test(o Tester) bool {
-	result := false
-	switch v := o.(type) {
-	case *Polygon:
-		result = TestCirclePolygon(c, v)
-	case *Circle:
-		result = TestCircleCircle(c, v)
-	case *Composit:
-		result = TestCircleComposit(c, v)
-	default:
-		result = false
-	}
-
-	return result
+	return TestShapes(c, o)
 }
 
 // 複合形状
 type Composit struct {
-	Collisions []Tester         // 型的にCompositにCompositを入れることができそうだが禁止とする
-	Operator   CompositOperator // 0:or、1:and
+	Collisions []Tester         // 子要素。Compositを入れて入れ子にできる
+	Operator   CompositOperator // 0:or、1:and、2:not、3:xor
+	Locals     []Transform      // 子要素ごとの親からの相対的な姿勢。Collisionsと同じ順番で、足りない分は親と同じ
+	Filter     Filter           // オブジェクト同士の判定や問い合わせの絞り込み条件。Testでは種類を見ない
 }
 
 func (c *Composit) Test(o Tester) bool {
-	result := false
-	switch v := o.(type) {
-	case *Polygon:
-		result = TestPolygonComposit(v, c)
-	case *Circle:
-		result = TestCircleComposit(v, c)
-	case *Composit:
-		result = TestCompositComposit(v, c)
-	default:
-		result = false
-	}
-
-	return result
+	return TestShapes(c, o)
 }
 
-// 複合形状のAnd/Or条件
+// 複合形状の子要素の組み合わせ方
 type CompositOperator int
 
 const (
-	CompositOr  CompositOperator = 0
-	CompositAnd CompositOperator = 1
+	CompositOr  CompositOperator = 0 // いずれかの子要素
+	CompositAnd CompositOperator = 1 // 全ての子要素の共通部分
+	CompositNot CompositOperator = 2 // 最初の子要素から残りの子要素を取り除いた部分
+	CompositXor CompositOperator = 3 // 奇数個の子要素に含まれる部分
 )
 
 // 点と円の判定
@@ -94,17 +84,13 @@ func TestPointCircle(x, y float64, c1 *Circle) bool {
 
 // 点と凸型多角形の判定
 func TestPointPolygon(x, y float64, c1 *Polygon) bool {
-	// 外積を計算して境界内に点があるかを判定する
-	r := make([]gmath.Vec, 0, len(c1.Vertices)+1)
-	for _, p := range c1.Vertices {
-		// 各頂点から回転原点を引いてから回転、回転原点とベース座標を足すことでグローバル座標を算出する
-		v := p.Sub(c1.Origin).Rotated(c1.Rad).Add(c1.Origin).Add(c1.Pos)
-		r = append(r, v)
+	// 頂点が足りない場合は面積が無いので当たらない
+	if len(c1.Vertices) < 3 {
+		return false
 	}
 
-	// 1個目の頂点をスライスに追加する
-	// これでr[n+1]-r[n]がエッジのベクトルになる
-	r = append(r, r[0])
+	// 外積を計算して境界内に点があるかを判定する
+	r := c1.worldVertices()
 
 	// エッジのベクトルと、r[n]からx,yまでのベクトルの外積を取って、点がエッジのどちら側にあるかを調べる
 	for i := 0; i < len(r)-1; i++ {
@@ -132,52 +118,43 @@ func TestCircleCircle(c1 *Circle, c2 *Circle) bool {
 
 // 円と凸型多角形の判定
 func TestCirclePolygon(c *Circle, p *Polygon) bool {
-	r := make([]gmath.Vec, 0, len(p.Vertices)+1)
-	for _, v := range p.Vertices {
-		// 各頂点から回転原点を引いてから回転、回転原点とベース座標を足すことでグローバル座標を算出する
-		v := v.Sub(p.Origin).Rotated(p.Rad).Add(p.Origin).Add(p.Pos)
-		r = append(r, v)
+	// 頂点が足りない場合は面積が無いので当たらない
+	if len(p.Vertices) < 3 {
+		return false
 	}
-
-	// 1個目の頂点をスライスに追加する
-	// これでr1[n+1]-r1[n]がエッジのベクトルになる
-	r = append(r, r[0])
+	r := p.worldVertices()
+	norms := p.edgeAxes() // エッジの正規化ベクトル
 
 	// エッジと円の中心点の位置関係を調べる
-	norms := make([]gmath.Vec, len(p.Vertices))      // エッジの正規化ベクトル
-	edges := make([]gmath.Vec, len(p.Vertices))      // エッジのベクトル
-	points := make([]gmath.Vec, len(p.Vertices))     // エッジの起点から円の中心へのベクトル
-	inside := true                                   // 円の中心が多角形の内側にあるフラグ
-	before_edge_flg := make([]bool, len(p.Vertices)) // エッジより前に円の中心があるフラグ
-	after_edge_flg := make([]bool, len(p.Vertices))  // エッジより後に円の中心があるフラグ
+	inside := true            // 円の中心が多角形の内側にあるフラグ
+	first_before_flg := false // 1個目のエッジより前に円の中心があるフラグ
+	after_edge_flg := false   // 前のエッジより後に円の中心があるフラグ
 
 	for i := 0; i < len(r)-1; i++ {
-		edges[i] = r[i+1].Sub(r[i])
-		norms[i] = edges[i].Normalized()
-		points[i] = c.Pos.Sub(r[i])
+		point := c.Pos.Sub(r[i]) // エッジの起点から円の中心へのベクトル
 
 		// エッジの端より前後にあるかを内積で求める
-		v := points[i].Dot(norms[i])
-		e := edges[i].Dot(norms[i])
-		before_edge_flg[i] = v <= 0 // 原点よりも手前
-		after_edge_flg[i] = v >= e  // エッジの終点よりも先
+		v := point.Dot(norms[i])
+		e := r[i+1].Sub(r[i]).Dot(norms[i])
+		before_edge_flg := v <= 0 // 原点よりも手前
 
-		if i > 0 { // 最初の1個の場合は前のエッジが無い
+		if i == 0 { // 最初の1個の場合は前のエッジが無い
+			first_before_flg = before_edge_flg
+		} else if after_edge_flg && before_edge_flg {
 			// 前のエッジよりも先で今のエッジよりも前の場合、エッジの起点の頂点が円に最も近い
-			if after_edge_flg[i-1] && before_edge_flg[i] {
-				// この頂点以外との衝突判定は必要ない
-				return TestPointCircle(r[i].X, r[i].Y, c)
-			}
+			// この頂点以外との衝突判定は必要ない
+			return TestPointCircle(r[i].X, r[i].Y, c)
 		}
+		after_edge_flg = v >= e // エッジの終点よりも先
 
 		// 外積を求めてどっち側にあるかを調べる
-		cp := points[i].X*norms[i].Y - norms[i].X*points[i].Y
+		cp := point.X*norms[i].Y - norms[i].X*point.Y
 		if cp > 0 {
 			// 外側にある
 			inside = false
 
 			// 頂点よりエッジのほうが近い場合、エッジと円の衝突判定をする
-			if !before_edge_flg[i] && !after_edge_flg[i] {
+			if !before_edge_flg && !after_edge_flg {
 				// エッジから円の中心までの最短距離は計算済
 				if cp < c.Radius {
 					return true
@@ -187,7 +164,7 @@ func TestCirclePolygon(c *Circle, p *Polygon) bool {
 	}
 
 	// 最後のエッジよりも先で1個目のエッジよりも前の場合、1個目のエッジの起点の頂点が円に最も近い
-	if after_edge_flg[len(r)-2] && before_edge_flg[0] {
+	if after_edge_flg && first_before_flg {
 		return TestPointCircle(r[0].X, r[0].Y, c)
 	}
 
@@ -197,88 +174,132 @@ func TestCirclePolygon(c *Circle, p *Polygon) bool {
 
 // 凸型多角形同士の判定(SAT)
 func TestPolygonPolygon(c1 *Polygon, c2 *Polygon) bool {
-	r1 := make([]gmath.Vec, 0, len(c1.Vertices)+1)
-	for _, p := range c1.Vertices {
-		// 各頂点から回転原点を引いてから回転、回転原点とベース座標を足すことでグローバル座標を算出する
-		v := p.Sub(c1.Origin).Rotated(c1.Rad).Add(c1.Origin).Add(c1.Pos)
-		r1 = append(r1, v)
+	// 頂点が足りない場合は面積が無いので当たらない
+	if len(c1.Vertices) < 3 || len(c2.Vertices) < 3 {
+		return false
 	}
+	r1 := c1.worldVertices()
+	r2 := c2.worldVertices()
 
-	// 1個目の頂点をスライスに追加する
-	// これでr1[n+1]-r1[n]がエッジのベクトルになる
-	r1 = append(r1, r1[0])
-
-	r2 := make([]gmath.Vec, 0, len(c2.Vertices)+1)
-	for _, p := range c2.Vertices {
-		// 各頂点から回転原点を引いてから回転、回転原点とベース座標を足すことでグローバル座標を算出する
-		v := p.Sub(c2.Origin).Rotated(c2.Rad).Add(c2.Origin).Add(c2.Pos)
-		r2 = append(r2, v)
+	// 各軸に各頂点を射影してmin/maxを求める
+	for _, axes := range [2][]gmath.Vec{c1.edgeAxes(), c2.edgeAxes()} {
+		for _, v := range axes {
+			min1, max1 := project(r1, v)
+			min2, max2 := project(r2, v)
+
+			// 範囲が重なっているかのチェック
+			if min1 > max2 || max1 < min2 {
+				// 重なっていない
+				return false
+			}
+		}
 	}
 
-	// 1個目の頂点をスライスに追加する
-	// これでr2[n+1]-r2[n]がエッジのベクトルになる
-	r2 = append(r2, r2[0])
+	// すべての軸で重なっていた
+	return true
+}
 
-	// 軸ベクトルを算出
-	vs := make([]gmath.Vec, 0, len(c1.Vertices)+len(c2.Vertices))
-	for i := 0; i < len(c1.Vertices); i++ {
-		vs = append(vs, r1[i+1].Sub(r1[i]).Normalized())
-	}
-	for i := 0; i < len(c2.Vertices); i++ {
-		vs = append(vs, r2[i+1].Sub(r2[i]).Normalized())
-	}
+// 頂点集合をグローバル座標に変換する
+// 1個目の頂点を末尾に追加するので、r[n+1]-r[n]がエッジのベクトルになる
+// キャッシュをそのまま返すので、書き換えてはいけない
+func (p *Polygon) worldVertices() []gmath.Vec {
+	return p.updateCache().world
+}
 
-	// 各軸に各頂点を射影してmin/maxを求める
-	for _, v := range vs {
-		s1 := make([]float64, 0, 4)
+// 正規化したエッジのベクトル
+func (p *Polygon) edgeAxes() []gmath.Vec {
+	return p.updateCache().axes
+}
 
-		// c1の頂点を射影
-		for i := 0; i < len(c1.Vertices); i++ {
-			s1 = append(s1, r1[i].X*v.Y-v.X*r1[i].Y)
-		}
+// 頂点集合の中身を直接書き換えた場合に呼んで、キャッシュを捨てる
+// Pos/Rad/Originの変更やVerticesの差し替えは自動で検出する
+func (p *Polygon) Invalidate() {
+	p.cache.owner = nil
+}
 
-		s2 := make([]float64, 0, 4)
+func (p *Polygon) updateCache() *polygonCache {
+	c := &p.cache
+	if c.owner == p && c.pos == p.Pos && c.rad == p.Rad && c.origin == p.Origin && c.scale == p.Scale && sameVertices(c.vertices, p.Vertices) {
+		return c
+	}
 
-		// c2の頂点を射影
-		for i := 0; i < len(c2.Vertices); i++ {
-			s2 = append(s2, r2[i].X*v.Y-v.X*r2[i].Y)
-		}
+	// コピー元のバッファを書き換えないように作り直す
+	if c.owner != p {
+		*c = polygonCache{owner: p}
+	}
+	c.pos = p.Pos
+	c.rad = p.Rad
+	c.origin = p.Origin
+	c.scale = p.Scale
+	c.vertices = p.Vertices
+
+	c.world = c.world[:0]
+	s := ScaleOf(p.Scale)
+	for _, v := range p.Vertices {
+		// 各頂点から回転原点を引いてから拡大・回転、回転原点とベース座標を足すことでグローバル座標を算出する
+		c.world = append(c.world, v.Sub(p.Origin).Mul(s).Rotated(p.Rad).Add(p.Origin).Add(p.Pos))
+	}
 
-		// min/max算出
-		min1 := slices.Min(s1)
-		max1 := slices.Max(s1)
-		min2 := slices.Min(s2)
-		max2 := slices.Max(s2)
+	// 片方の軸だけ反転すると左回りになるので並びを戻す
+	if s.X*s.Y < 0 {
+		slices.Reverse(c.world)
+	}
+	c.axes = c.axes[:0]
+	if len(c.world) == 0 {
+		c.bounds = gmath.Rect{}
+		return c
+	}
+	c.world = append(c.world, c.world[0])
 
-		// 範囲が重なっているかのチェック
-		if min1 > max2 || max1 < min2 {
-			// 重なっていない
-			return false
-		}
+	c.bounds = gmath.Rect{Min: c.world[0], Max: c.world[0]}
+	for i := 0; i < len(c.world)-1; i++ {
+		c.axes = append(c.axes, c.world[i+1].Sub(c.world[i]).Normalized())
+		c.bounds = extendRect(c.bounds, c.world[i])
 	}
+	return c
+}
 
-	// すべての軸で重なっていた
-	return true
+// 同じ頂点集合を指しているか
+func sameVertices(a, b []gmath.Vec) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// 頂点を軸に射影してmin/maxを求める
+// 外積で射影するので、軸ベクトルを右に90度回したベクトル(エッジの外向き法線)方向の値になる
+func project(r []gmath.Vec, v gmath.Vec) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := 0; i < len(r)-1; i++ {
+		s := r[i].X*v.Y - v.X*r[i].Y
+		lo = min(lo, s)
+		hi = max(hi, s)
+	}
+	return lo, hi
 }
 
 // 点と複合形状の判定
 func TestPointComposit(x, y float64, co *Composit) bool {
 	result := false
-	for _, d := range co.Collisions {
-		switch v := d.(type) {
-		case *Polygon:
-			result = TestPointPolygon(x, y, v)
-		case *Circle:
-			result = TestPointCircle(x, y, v)
-		default:
-			result = false
-		}
+	for i, d := range co.Collisions {
+		hit := TestPoint(x, y, d)
 
-		if co.Operator == CompositOr && result {
-			return true
-		}
-		if co.Operator == CompositAnd && !result {
-			return false
+		switch co.Operator {
+		case CompositOr:
+			if hit {
+				return true
+			}
+		case CompositAnd:
+			if !hit {
+				return false
+			}
+			result = true
+		case CompositNot:
+			// 最初の子要素の内側で、残りの子要素の外側
+			if hit != (i == 0) {
+				return false
+			}
+			result = true
+		case CompositXor:
+			result = result != hit
 		}
 	}
 
@@ -287,58 +308,38 @@ func TestPointComposit(x, y float64, co *Composit) bool {
 
 // 円と複合形状の判定
 func TestCircleComposit(c *Circle, co *Composit) bool {
-	result := false
-	for _, d := range co.Collisions {
-		result = c.Test(d)
-
-		if co.Operator == CompositOr && result {
-			return true
-		}
-		if co.Operator == CompositAnd && !result {
-			return false
-		}
-	}
-
-	return result
+	return testComposit(c, co)
 }
 
 func TestPolygonComposit(p *Polygon, co *Composit) bool {
-	result := false
-	for _, d := range co.Collisions {
-		result = p.Test(d)
-
-		if co.Operator == CompositOr && result {
-			return true
-		}
-		if co.Operator == CompositAnd && !result {
-			return false
-		}
-	}
-
-	return result
+	return testComposit(p, co)
 }
 
 func TestCompositComposit(c1 *Composit, c2 *Composit) bool {
-	result := false
-	for _, d1 := range c1.Collisions {
-		for _, d2 := range c2.Collisions {
-			result = d1.Test(d2)
-
-			if c2.Operator == CompositOr && result {
-				break
-			}
-			if c2.Operator == CompositAnd && !result {
-				break
+	switch {
+	case c2.isRegion():
+		// Not/Xorは組み合わせた領域の断片で判定する
+		return testRegion(c1, c2)
+	case c1.isRegion():
+		return testRegion(c2, c1)
+	case c1.Operator == CompositAnd && c2.Operator == CompositAnd:
+		// And同士は共通部分同士で判定する
+		return testIntersection(c1, c2)
+	case c1.Operator == CompositAnd:
+		// c2のいずれかの子要素がc1の共通部分と重なっている
+		for _, d := range c2.Collisions {
+			if d.Test(c1) {
+				return true
 			}
 		}
+		return false
+	}
 
-		if c1.Operator == CompositOr && result {
+	// c1のいずれかの子要素がc2と重なっている
+	for _, d := range c1.Collisions {
+		if d.Test(c2) {
 			return true
 		}
-		if c1.Operator == CompositAnd && !result {
-			return false
-		}
 	}
-
-	return result
+	return false
 }
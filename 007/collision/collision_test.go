@@ -0,0 +1,126 @@
+package collision
+
+import (
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// ベンチマーク用の重なっている形状
+func benchShapes() (*Polygon, *Polygon, *Circle) {
+	p1 := &Polygon{
+		Pos:      gmath.Vec{X: 100, Y: 100},
+		Rad:      0.3,
+		Vertices: []gmath.Vec{{X: -20, Y: -20}, {X: 20, Y: -20}, {X: 30, Y: 10}, {X: 0, Y: 30}, {X: -30, Y: 10}},
+	}
+	p2 := &Polygon{
+		Pos:      gmath.Vec{X: 130, Y: 110},
+		Rad:      -0.5,
+		Vertices: []gmath.Vec{{X: -15, Y: -15}, {X: 15, Y: -15}, {X: 15, Y: 15}, {X: -15, Y: 15}},
+	}
+	c := &Circle{Pos: gmath.Vec{X: 125, Y: 95}, Radius: 12}
+	return p1, p2, c
+}
+
+// 判定でメモリを確保しない
+// 動かしてキャッシュを作り直す場合も、作った後のバッファを使い回す
+func TestNarrowPhaseAllocs(t *testing.T) {
+	p1, p2, c := benchShapes()
+	i := 0
+	move := func() {
+		i++
+		p1.Pos.X = 100 + float64(i%8)
+		p2.Rad = gmath.Rad(i%16) * 0.1
+	}
+
+	tests := []struct {
+		name string
+		f    func()
+	}{
+		{"TestPolygonPolygon", func() { TestPolygonPolygon(p1, p2) }},
+		{"TestCirclePolygon", func() { TestCirclePolygon(c, p1) }},
+		{"TestPointPolygon", func() { TestPointPolygon(c.Pos.X, c.Pos.Y, p1) }},
+		{"TestPolygonPolygon moving", func() { move(); TestPolygonPolygon(p1, p2) }},
+		{"TestCirclePolygon moving", func() { move(); TestCirclePolygon(c, p1) }},
+		{"TestPointPolygon moving", func() { move(); TestPointPolygon(c.Pos.X, c.Pos.Y, p1) }},
+	}
+	for _, tt := range tests {
+		if n := testing.AllocsPerRun(100, tt.f); n != 0 {
+			t.Errorf("%s: %v allocs per run, want 0", tt.name, n)
+		}
+	}
+}
+
+func BenchmarkTestPolygonPolygon(b *testing.B) {
+	p1, p2, _ := benchShapes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		TestPolygonPolygon(p1, p2)
+	}
+}
+
+func BenchmarkTestPolygonPolygonMoving(b *testing.B) {
+	p1, p2, _ := benchShapes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p1.Pos.X = 100 + float64(i%8)
+		TestPolygonPolygon(p1, p2)
+	}
+}
+
+func BenchmarkTestCirclePolygon(b *testing.B) {
+	p, _, c := benchShapes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		TestCirclePolygon(c, p)
+	}
+}
+
+func BenchmarkTestPointPolygon(b *testing.B) {
+	p, _, c := benchShapes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		TestPointPolygon(c.Pos.X, c.Pos.Y, p)
+	}
+}
+
+func BenchmarkCollidePolygonPolygon(b *testing.B) {
+	p1, p2, _ := benchShapes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		CollidePolygonPolygon(p1, p2)
+	}
+}
+
+// 頂点が3個より少ない多角形は面積が無いので、どの判定でも当たらない
+func TestDegeneratePolygon(t *testing.T) {
+	for _, vs := range [][]gmath.Vec{
+		nil,
+		{{X: 0, Y: 0}},
+		{{X: -20, Y: 0}, {X: 20, Y: 0}},
+	} {
+		p := &Polygon{Pos: gmath.Vec{X: 100, Y: 100}, Vertices: vs}
+		c := &Circle{Pos: gmath.Vec{X: 100, Y: 100}, Radius: 10}
+		q, _, _ := benchShapes()
+		q.Pos = p.Pos
+
+		if TestCirclePolygon(c, p) {
+			t.Errorf("%d vertices: TestCirclePolygon = true", len(vs))
+		}
+		if _, ok := CollideCirclePolygon(c, p); ok {
+			t.Errorf("%d vertices: CollideCirclePolygon = true", len(vs))
+		}
+		if TestPolygonPolygon(q, p) || TestPolygonPolygon(p, q) {
+			t.Errorf("%d vertices: TestPolygonPolygon = true", len(vs))
+		}
+		if _, ok := CollidePolygonPolygon(q, p); ok {
+			t.Errorf("%d vertices: CollidePolygonPolygon = true", len(vs))
+		}
+		if TestPointPolygon(100, 100, p) {
+			t.Errorf("%d vertices: TestPointPolygon = true", len(vs))
+		}
+		if _, ok := RaycastPolygon(gmath.Vec{X: 100, Y: 50}, gmath.Vec{Y: 1}, 100, p); ok {
+			t.Errorf("%d vertices: RaycastPolygon = true", len(vs))
+		}
+	}
+}
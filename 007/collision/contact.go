@@ -0,0 +1,399 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 衝突情報を返せる衝突判定範囲のインターフェース
+type Collider interface {
+	Tester
+	Collide(c Tester) (Contact, bool)
+}
+
+// 衝突情報
+type Contact struct {
+	Normal gmath.Vec   // 衝突法線(自分から相手に向かう単位ベクトル)
+	Depth  float64     // めり込み量
+	Points []gmath.Vec // 接触点
+}
+
+// 相手をこのベクトル分移動させると離れる(最小移動ベクトル)
+func (c Contact) MTV() gmath.Vec {
+	return c.Normal.Mulf(c.Depth)
+}
+
+// 自分と相手を入れ替えた衝突情報を返す
+func (c Contact) Reversed() Contact {
+	c.Normal = c.Normal.Neg()
+	return c
+}
+
+func (p *Polygon) Collide(o Tester) (Contact, bool) {
+	o = scaledShape(o)
+	switch v := o.(type) {
+	case *Polygon:
+		return CollidePolygonPolygon(p, v)
+	case *Circle:
+		c, ok := CollideCirclePolygon(v, p)
+		return c.Reversed(), ok
+	case *Composit:
+		return CollidePolygonComposit(p, v)
+	}
+
+	return collideShapes(p, o)
+}
+
+func (c *Circle) Collide(o Tester) (Contact, bool) {
+	// 拡大している場合は変換後の形状で求める
+	if s := scaledShape(c); s != Tester(c) {
+		return s.(Collider).Collide(o)
+	}
+
+	o = scaledShape(o)
+	switch v := o.(type) {
+	case *Polygon:
+		return CollideCirclePolygon(c, v)
+	case *Circle:
+		return CollideCircleCircle(c, v)
+	case *Composit:
+		return CollideCircleComposit(c, v)
+	}
+
+	return collideShapes(c, o)
+}
+
+func (c *Composit) Collide(o Tester) (Contact, bool) {
+	o = scaledShape(o)
+	switch v := o.(type) {
+	case *Polygon:
+		r, ok := CollidePolygonComposit(v, c)
+		return r.Reversed(), ok
+	case *Circle:
+		r, ok := CollideCircleComposit(v, c)
+		return r.Reversed(), ok
+	case *Composit:
+		return CollideCompositComposit(c, v)
+	}
+
+	r, ok := collideShapes(o, c)
+	return r.Reversed(), ok
+}
+
+func (c *Capsule) Collide(o Tester) (Contact, bool) {
+	return collideShapes(c, o)
+}
+
+func (s *Segment) Collide(o Tester) (Contact, bool) {
+	return collideShapes(s, o)
+}
+
+func (e *Ellipse) Collide(o Tester) (Contact, bool) {
+	return collideShapes(e, o)
+}
+
+func (b *AABB) Collide(o Tester) (Contact, bool) {
+	return collideShapes(b, o)
+}
+
+// 専用の処理が無い組み合わせの衝突情報
+// 凸形状同士はEPAで、それ以外は多角形で近似した断片同士で求める
+func collideShapes(a, b Tester) (Contact, bool) {
+	a = scaledShape(a)
+	b = scaledShape(b)
+	if v, ok := b.(*Composit); ok {
+		return collideComposit(v, func(d Tester) (Contact, bool) {
+			return collideShapes(a, d)
+		})
+	}
+	if !a.Test(b) {
+		return Contact{}, false
+	}
+
+	ca, ok1 := a.(Convex)
+	cb, ok2 := b.(Convex)
+	if ok1 && ok2 {
+		if c, ok := ConvexPenetration(ca, cb); ok {
+			return c, true
+		}
+	}
+
+	var result Contact
+	found := false
+	for _, p := range Pieces(a) {
+		for _, q := range Pieces(b) {
+			c, ok := CollidePolygonPolygon(&Polygon{Vertices: p}, &Polygon{Vertices: q})
+			if ok && (!found || c.Depth > result.Depth) {
+				result = c
+				found = true
+			}
+		}
+	}
+	return result, found
+}
+
+// 円同士の衝突情報
+func CollideCircleCircle(c1 *Circle, c2 *Circle) (Contact, bool) {
+	if !TestCircleCircle(c1, c2) {
+		return Contact{}, false
+	}
+
+	d := c2.Pos.Sub(c1.Pos)
+	l := d.Len()
+
+	// 中心が同じ場合は押し出す方向が決まらないので適当にX軸方向とする
+	n := gmath.Vec{X: 1, Y: 0}
+	if l != 0 {
+		n = d.Divf(l)
+	}
+
+	depth := c1.Radius + c2.Radius - l
+	return Contact{
+		Normal: n,
+		Depth:  depth,
+		Points: []gmath.Vec{c1.Pos.Add(n.Mulf(c1.Radius - depth*0.5))},
+	}, true
+}
+
+// 円と凸型多角形の衝突情報
+func CollideCirclePolygon(c *Circle, p *Polygon) (Contact, bool) {
+	// 頂点が足りない場合は面積が無いので当たらない
+	if len(p.Vertices) < 3 {
+		return Contact{}, false
+	}
+	r := p.worldVertices()
+
+	inside := true          // 円の中心が多角形の内側にあるフラグ
+	maxDist := math.Inf(-1) // エッジから円の中心までの符号付き距離の最大値
+	var maxNorm gmath.Vec   // その時のエッジの外向き法線
+	minDist2 := math.Inf(1) // 多角形の外周から円の中心までの距離の2乗の最小値
+	var closest gmath.Vec   // その時の外周上の最近点
+
+	for i := 0; i < len(r)-1; i++ {
+		e := r[i+1].Sub(r[i])
+		n := gmath.Vec{X: e.Y, Y: -e.X}.Normalized()
+
+		// 正なら外側
+		d := c.Pos.Sub(r[i]).Dot(n)
+		if d > 0 {
+			inside = false
+		}
+		if d > maxDist {
+			maxDist = d
+			maxNorm = n
+		}
+
+		q := closestPointOnSegment(c.Pos, r[i], r[i+1])
+		if d2 := q.DistanceSquaredTo(c.Pos); d2 < minDist2 {
+			minDist2 = d2
+			closest = q
+		}
+	}
+
+	// 中心が内側にある場合は一番近いエッジから押し出す
+	if inside {
+		return Contact{
+			Normal: maxNorm.Neg(),
+			Depth:  c.Radius - maxDist,
+			Points: []gmath.Vec{c.Pos.Sub(maxNorm.Mulf(maxDist))},
+		}, true
+	}
+
+	// 外側にある場合は外周上の最近点との距離で判定する
+	if minDist2 >= c.Radius*c.Radius {
+		return Contact{}, false
+	}
+	l := math.Sqrt(minDist2)
+	return Contact{
+		Normal: closest.Sub(c.Pos).Divf(l),
+		Depth:  c.Radius - l,
+		Points: []gmath.Vec{closest},
+	}, true
+}
+
+// 凸型多角形同士の衝突情報(SAT)
+func CollidePolygonPolygon(c1 *Polygon, c2 *Polygon) (Contact, bool) {
+	// 頂点が足りない場合は面積が無いので当たらない
+	if len(c1.Vertices) < 3 || len(c2.Vertices) < 3 {
+		return Contact{}, false
+	}
+	r1 := c1.worldVertices()
+	r2 := c2.worldVertices()
+
+	depth := math.Inf(1)
+	var normal gmath.Vec
+
+	// TestPolygonPolygonと同じ軸で判定して、一番重なりが小さい軸を探す
+	for _, axes := range [2][]gmath.Vec{c1.edgeAxes(), c2.edgeAxes()} {
+		for _, v := range axes {
+			min1, max1 := project(r1, v)
+			min2, max2 := project(r2, v)
+
+			if min1 > max2 || max1 < min2 {
+				return Contact{}, false
+			}
+
+			// 射影の方向(エッジの外向き法線)
+			n := gmath.Vec{X: v.Y, Y: -v.X}
+
+			// c2を正の方向に押し出す場合と負の方向に押し出す場合
+			if d := max1 - min2; d < depth {
+				depth = d
+				normal = n
+			}
+			if d := max2 - min1; d < depth {
+				depth = d
+				normal = n.Neg()
+			}
+		}
+	}
+
+	return Contact{
+		Normal: normal,
+		Depth:  depth,
+		Points: clipContactPoints(r1, r2, normal),
+	}, true
+}
+
+// 点と線分上の最近点
+func closestPointOnSegment(p, a, b gmath.Vec) gmath.Vec {
+	ab := b.Sub(a)
+	l := ab.LenSquared()
+	if l == 0 {
+		return a
+	}
+	t := gmath.Clamp(p.Sub(a).Dot(ab)/l, 0, 1)
+	return a.Add(ab.Mulf(t))
+}
+
+// 接触点算出用のエッジ
+type contactEdge struct {
+	max gmath.Vec // 法線方向に一番遠い頂点
+	v1  gmath.Vec // エッジの始点
+	v2  gmath.Vec // エッジの終点
+}
+
+// 法線方向に一番遠い頂点を含むエッジのうち、法線と垂直に近いほうを返す
+func bestEdge(r []gmath.Vec, n gmath.Vec) contactEdge {
+	num := len(r) - 1
+	idx := 0
+	max := math.Inf(-1)
+	for i := 0; i < num; i++ {
+		if d := r[i].Dot(n); d > max {
+			max = d
+			idx = i
+		}
+	}
+
+	v := r[idx]
+	next := r[(idx+1)%num]
+	prev := r[(idx+num-1)%num]
+	l := v.Sub(prev).Normalized()
+	rr := next.Sub(v).Normalized()
+	if math.Abs(rr.Dot(n)) <= math.Abs(l.Dot(n)) {
+		return contactEdge{max: v, v1: v, v2: next}
+	}
+	return contactEdge{max: v, v1: prev, v2: v}
+}
+
+// 線分を、n方向の値がo以上の部分に切り取る
+func clipSegment(v1, v2, n gmath.Vec, o float64) []gmath.Vec {
+	cp := make([]gmath.Vec, 0, 2)
+	d1 := n.Dot(v1) - o
+	d2 := n.Dot(v2) - o
+	if d1 >= 0 {
+		cp = append(cp, v1)
+	}
+	if d2 >= 0 {
+		cp = append(cp, v2)
+	}
+	if d1*d2 < 0 {
+		cp = append(cp, v1.Add(v2.Sub(v1).Mulf(d1/(d1-d2))))
+	}
+	return cp
+}
+
+// 凸型多角形同士の接触点をエッジのクリッピングで求める
+// nはr1からr2に向かう衝突法線
+func clipContactPoints(r1, r2 []gmath.Vec, n gmath.Vec) []gmath.Vec {
+	e1 := bestEdge(r1, n)
+	e2 := bestEdge(r2, n.Neg())
+
+	// 法線と垂直に近いほうを基準エッジ、もう一方を入射エッジとする
+	ref, inc := e1, e2
+	if math.Abs(e2.v2.Sub(e2.v1).Normalized().Dot(n)) < math.Abs(e1.v2.Sub(e1.v1).Normalized().Dot(n)) {
+		ref, inc = e2, e1
+	}
+
+	// 基準エッジの両端で入射エッジを切り取る
+	refv := ref.v2.Sub(ref.v1).Normalized()
+	cp := clipSegment(inc.v1, inc.v2, refv, refv.Dot(ref.v1))
+	if len(cp) < 2 {
+		return []gmath.Vec{inc.max}
+	}
+	cp = clipSegment(cp[0], cp[1], refv.Neg(), -refv.Dot(ref.v2))
+	if len(cp) < 2 {
+		return []gmath.Vec{inc.max}
+	}
+
+	// 基準エッジより外側の点は接触していないので取り除く
+	refNorm := gmath.Vec{X: refv.Y, Y: -refv.X}
+	o := refNorm.Dot(ref.v1)
+	points := make([]gmath.Vec, 0, 2)
+	for _, p := range cp {
+		if refNorm.Dot(p) <= o {
+			points = append(points, p)
+		}
+	}
+	if len(points) == 0 {
+		return []gmath.Vec{inc.max}
+	}
+	return points
+}
+
+// 複合形状の子要素ごとの衝突情報をまとめる
+// Orの場合は子要素、それ以外の場合は領域の断片ごとに判定して一番深い衝突を採用する
+func collideComposit(co *Composit, f func(Tester) (Contact, bool)) (Contact, bool) {
+	// センサーは重なりを検知するだけ
+	if co.Filter.Sensor {
+		return Contact{}, false
+	}
+
+	children := co.Collisions
+	if co.Operator != CompositOr {
+		children = pieceTesters(co)
+	}
+
+	var result Contact
+	found := false
+	for _, d := range children {
+		if c, ok := f(d); ok && (!found || c.Depth > result.Depth) {
+			result = c
+			found = true
+		}
+	}
+
+	return result, found
+}
+
+// 円と複合形状の衝突情報
+func CollideCircleComposit(c *Circle, co *Composit) (Contact, bool) {
+	return collideComposit(co, c.Collide)
+}
+
+// 凸型多角形と複合形状の衝突情報
+func CollidePolygonComposit(p *Polygon, co *Composit) (Contact, bool) {
+	return collideComposit(co, p.Collide)
+}
+
+// 複合形状同士の衝突情報
+func CollideCompositComposit(c1 *Composit, c2 *Composit) (Contact, bool) {
+	return collideComposit(c1, func(d Tester) (Contact, bool) {
+		v, ok := d.(Collider)
+		if !ok {
+			return Contact{}, false
+		}
+		return v.Collide(c2)
+	})
+}
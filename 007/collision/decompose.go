@@ -0,0 +1,235 @@
+package collision
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/quasilyte/gmath"
+)
+
+var (
+	ErrTooFewVertices   = errors.New("collision: polygon needs at least 3 vertices")
+	ErrSelfIntersecting = errors.New("collision: polygon edges intersect each other")
+	ErrDecompose        = errors.New("collision: polygon could not be decomposed")
+)
+
+// 凹型を含む任意の単純多角形から複合形状を作る
+// 頂点集合は右回りでも左回りでもよい。凸型多角形に分解してOrでまとめる
+func NewConcave(vs []gmath.Vec) (*Composit, error) {
+	pieces, err := Decompose(vs)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Composit{Operator: CompositOr}
+	for _, p := range pieces {
+		c.Collisions = append(c.Collisions, &Polygon{Vertices: p})
+	}
+	return c, nil
+}
+
+// 単純多角形を右回りの凸型多角形に分解する
+// 耳刈り取り法で三角形に分割したあと、Hertel-Mehlhornで凸のまま結合できる対角線を消す
+func Decompose(vs []gmath.Vec) ([][]gmath.Vec, error) {
+	if len(vs) < 3 {
+		return nil, ErrTooFewVertices
+	}
+	if selfIntersecting(vs) {
+		return nil, ErrSelfIntersecting
+	}
+
+	// 右回りにそろえる
+	vs = slices.Clone(vs)
+	if polygonArea(vs) < 0 {
+		slices.Reverse(vs)
+	}
+
+	tris, err := triangulate(vs)
+	if err != nil {
+		return nil, err
+	}
+	polys := mergeConvex(vs, tris)
+
+	result := make([][]gmath.Vec, 0, len(polys))
+	for _, p := range polys {
+		r := make([]gmath.Vec, 0, len(p))
+		for _, i := range p {
+			r = append(r, vs[i])
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// 頂点bで曲がる向き(右回りの凸なら正)
+func turn(a, b, c gmath.Vec) float64 {
+	e1 := b.Sub(a)
+	e2 := c.Sub(b)
+	return e1.X*e2.Y - e1.Y*e2.X
+}
+
+// 点pが三角形abc(右回り)の内側または境界上にあるか
+func inTriangle(p, a, b, c gmath.Vec) bool {
+	return turn(a, b, p) >= 0 && turn(b, c, p) >= 0 && turn(c, a, p) >= 0
+}
+
+// 耳刈り取り法で三角形分割する
+// 頂点のインデックスの組を返す
+func triangulate(vs []gmath.Vec) ([][]int, error) {
+	idx := make([]int, len(vs))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	tris := make([][]int, 0, len(vs)-2)
+	for len(idx) > 3 {
+		found := false
+		for i := range idx {
+			ia := idx[(i+len(idx)-1)%len(idx)]
+			ib := idx[i]
+			ic := idx[(i+1)%len(idx)]
+			a, b, c := vs[ia], vs[ib], vs[ic]
+
+			// 凹んでいる頂点は耳にならない
+			if turn(a, b, c) <= 0 {
+				continue
+			}
+
+			// 他の頂点が三角形の中にあれば耳にならない
+			ear := true
+			for _, j := range idx {
+				if j == ia || j == ib || j == ic {
+					continue
+				}
+				if inTriangle(vs[j], a, b, c) {
+					ear = false
+					break
+				}
+			}
+			if !ear {
+				continue
+			}
+
+			tris = append(tris, []int{ia, ib, ic})
+			idx = slices.Delete(idx, i, i+1)
+			found = true
+			break
+		}
+
+		if !found {
+			// 一直線に並んだ頂点しか残っていない場合は面積が無いので捨てる
+			if polygonArea(pick(vs, idx)) <= pieceEpsilon {
+				return tris, nil
+			}
+			return nil, ErrDecompose
+		}
+	}
+
+	if turn(vs[idx[0]], vs[idx[1]], vs[idx[2]]) > 0 {
+		tris = append(tris, idx)
+	}
+	return tris, nil
+}
+
+// 隣り合う多角形を、結合しても凸のままなら結合する
+func mergeConvex(vs []gmath.Vec, polys [][]int) [][]int {
+	for merged := true; merged; {
+		merged = false
+	search:
+		for i := 0; i < len(polys); i++ {
+			for j := i + 1; j < len(polys); j++ {
+				m := joinPolygons(polys[i], polys[j])
+				if m == nil || !convexIndices(vs, m) {
+					continue
+				}
+				polys[i] = m
+				polys = slices.Delete(polys, j, j+1)
+				merged = true
+				break search
+			}
+		}
+	}
+	return polys
+}
+
+// 共有しているエッジで2つの多角形をつなげる
+// 共有しているエッジが無い場合はnilを返す
+func joinPolygons(p, q []int) []int {
+	for i := range p {
+		a := p[i]
+		b := p[(i+1)%len(p)]
+		for j := range q {
+			// 同じ向きの多角形同士なので共有エッジは逆向きになる
+			if q[j] != b || q[(j+1)%len(q)] != a {
+				continue
+			}
+
+			// pをbから始まりaで終わるように、qをaから始まりbで終わるように並べてつなぐ
+			r := make([]int, 0, len(p)+len(q)-2)
+			for k := 0; k < len(p); k++ {
+				r = append(r, p[(i+1+k)%len(p)])
+			}
+			for k := 1; k < len(q)-1; k++ {
+				r = append(r, q[(j+1+k)%len(q)])
+			}
+			return r
+		}
+	}
+	return nil
+}
+
+// インデックスで指定した多角形が凸か
+func convexIndices(vs []gmath.Vec, p []int) bool {
+	for i := range p {
+		if turn(vs[p[i]], vs[p[(i+1)%len(p)]], vs[p[(i+2)%len(p)]]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func pick(vs []gmath.Vec, idx []int) []gmath.Vec {
+	r := make([]gmath.Vec, 0, len(idx))
+	for _, i := range idx {
+		r = append(r, vs[i])
+	}
+	return r
+}
+
+// 隣り合っていないエッジ同士が交差しているか
+func selfIntersecting(vs []gmath.Vec) bool {
+	n := len(vs)
+	for i := 0; i < n; i++ {
+		a1 := vs[i]
+		a2 := vs[(i+1)%n]
+		for j := i + 2; j < n; j++ {
+			// 最初と最後のエッジは隣り合っている
+			if i == 0 && j == n-1 {
+				continue
+			}
+			if segmentsIntersect(a1, a2, vs[j], vs[(j+1)%n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 線分同士が交差しているか(端点で接している場合も含む)
+func segmentsIntersect(a1, a2, b1, b2 gmath.Vec) bool {
+	d1 := turn(b1, b2, a1)
+	d2 := turn(b1, b2, a2)
+	d3 := turn(a1, a2, b1)
+	d4 := turn(a1, a2, b2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+
+	onSegment := func(p, a, b gmath.Vec) bool {
+		return min(a.X, b.X) <= p.X && p.X <= max(a.X, b.X) && min(a.Y, b.Y) <= p.Y && p.Y <= max(a.Y, b.Y)
+	}
+	return (d1 == 0 && onSegment(a1, b1, b2)) ||
+		(d2 == 0 && onSegment(a2, b1, b2)) ||
+		(d3 == 0 && onSegment(b1, a1, a2)) ||
+		(d4 == 0 && onSegment(b2, a1, a2))
+}
@@ -0,0 +1,92 @@
+package collision
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 星形の頂点集合(右回り)
+func starVertices(n int, outer, inner float64) []gmath.Vec {
+	vs := make([]gmath.Vec, 0, n*2)
+	for i := 0; i < n*2; i++ {
+		r := outer
+		if i%2 == 1 {
+			r = inner
+		}
+		a := math.Pi * float64(i) / float64(n)
+		vs = append(vs, gmath.Vec{X: math.Cos(a) * r, Y: math.Sin(a) * r})
+	}
+	return vs
+}
+
+func reversed(vs []gmath.Vec) []gmath.Vec {
+	r := make([]gmath.Vec, 0, len(vs))
+	for i := len(vs) - 1; i >= 0; i-- {
+		r = append(r, vs[i])
+	}
+	return r
+}
+
+func TestDecomposeArea(t *testing.T) {
+	comb := []gmath.Vec{{X: 0, Y: 0}, {X: 100, Y: 0}, {X: 100, Y: 60}}
+	for i := 4; i >= 0; i-- {
+		x := float64(i) * 20
+		comb = append(comb, gmath.Vec{X: x + 10, Y: 60}, gmath.Vec{X: x + 10, Y: 20}, gmath.Vec{X: x, Y: 20})
+		if i > 0 {
+			comb = append(comb, gmath.Vec{X: x, Y: 60})
+		}
+	}
+
+	tests := []struct {
+		name string
+		vs   []gmath.Vec
+	}{
+		{"square", []gmath.Vec{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}},
+		{"L", []gmath.Vec{{X: 0, Y: 0}, {X: 30, Y: 0}, {X: 30, Y: 10}, {X: 10, Y: 10}, {X: 10, Y: 30}, {X: 0, Y: 30}}},
+		{"star", starVertices(5, 50, 20)},
+		{"star left-wound", reversed(starVertices(7, 40, 10))},
+		{"comb", comb},
+		{"collinear", []gmath.Vec{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces, err := Decompose(tt.vs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := math.Abs(polygonArea(tt.vs))
+			sum := 0.0
+			for _, p := range pieces {
+				a := polygonArea(p)
+				if a <= 0 {
+					t.Errorf("piece %v is not right-wound", p)
+				}
+				for i := range p {
+					if turn(p[i], p[(i+1)%len(p)], p[(i+2)%len(p)]) < -1e-9 {
+						t.Errorf("piece %v is not convex", p)
+						break
+					}
+				}
+				sum += a
+			}
+			if math.Abs(sum-want) > 1e-6*want {
+				t.Errorf("area sum = %v, want %v", sum, want)
+			}
+		})
+	}
+}
+
+func TestDecomposeErrors(t *testing.T) {
+	if _, err := Decompose([]gmath.Vec{{X: 0, Y: 0}, {X: 1, Y: 0}}); !errors.Is(err, ErrTooFewVertices) {
+		t.Errorf("two vertices: err = %v", err)
+	}
+	bowtie := []gmath.Vec{{X: 0, Y: 0}, {X: 10, Y: 10}, {X: 10, Y: 0}, {X: 0, Y: 10}}
+	if _, err := Decompose(bowtie); !errors.Is(err, ErrSelfIntersecting) {
+		t.Errorf("bowtie: err = %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package collision
+
+// 衝突の絞り込み条件
+// ゼロ値は既定の種類(1ビット目)に属し、全ての種類と衝突する
+type Filter struct {
+	Category    uint32 // 自分が属する種類のビット。0なら1ビット目
+	Mask        uint32 // 衝突する相手の種類のビット。0なら全て
+	CollideNone bool   // trueならMaskに関係なく何とも衝突しない。0のMaskは全てになるので、何とも衝突しない場合はこちらを使う
+	Sensor      bool   // 重なりを検知するだけで、Collideで衝突情報を返さない。レイやシェイプキャストも遮らない
+}
+
+func (f Filter) category() uint32 {
+	if f.Category == 0 {
+		return 1
+	}
+	return f.Category
+}
+
+func (f Filter) mask() uint32 {
+	if f.Mask == 0 {
+		return ^uint32(0)
+	}
+	return f.Mask
+}
+
+// お互いの種類が相手の衝突する種類に含まれているか
+// どちらかがCollideNoneなら衝突しない
+func (f Filter) ShouldCollide(o Filter) bool {
+	if f.CollideNone || o.CollideNone {
+		return false
+	}
+	return f.category()&o.mask() != 0 && o.category()&f.mask() != 0
+}
+
+// 問い合わせで指定した種類のビットに含まれているか
+// maskが0なら全てに含まれる。問い合わせは衝突ではないので、CollideNoneでも対象になる
+func (f Filter) Matches(mask uint32) bool {
+	return mask == 0 || f.category()&mask != 0
+}
+
+// レイやシェイプキャストの問い合わせで当たるか
+// センサーは重なりを検知するだけなので当たらない。点や矩形の問い合わせはMatchesで判定する
+func (f Filter) Hits(mask uint32) bool {
+	return !f.Sensor && f.Matches(mask)
+}
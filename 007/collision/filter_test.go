@@ -0,0 +1,55 @@
+package collision
+
+import "testing"
+
+func TestFilterShouldCollide(t *testing.T) {
+	const player, enemy, bullet = 1 << 0, 1 << 1, 1 << 2
+	tests := []struct {
+		name string
+		a, b Filter
+		want bool
+	}{
+		{"zero values", Filter{}, Filter{}, true},
+		{"zero mask against a custom category", Filter{}, Filter{Category: enemy}, true},
+		{"masks include each other", Filter{Category: player, Mask: enemy}, Filter{Category: enemy, Mask: player}, true},
+		{"one side excludes the other", Filter{Category: player, Mask: enemy}, Filter{Category: bullet}, false},
+		{"default category excluded by a mask", Filter{}, Filter{Category: enemy, Mask: bullet}, false},
+		{"collide none against zero values", Filter{CollideNone: true}, Filter{}, false},
+		{"collide none with a matching mask", Filter{Category: player, Mask: enemy, CollideNone: true}, Filter{Category: enemy, Mask: player}, false},
+		{"sensor still overlaps", Filter{Sensor: true}, Filter{}, true},
+		{"sensor follows its mask", Filter{Category: player, Mask: enemy, Sensor: true}, Filter{Category: bullet}, false},
+	}
+	for _, tt := range tests {
+		// 判定は対称
+		if got := tt.a.ShouldCollide(tt.b); got != tt.want {
+			t.Errorf("%s: a.ShouldCollide(b) = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tt.b.ShouldCollide(tt.a); got != tt.want {
+			t.Errorf("%s: b.ShouldCollide(a) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterHits(t *testing.T) {
+	const player, enemy = 1 << 0, 1 << 1
+	tests := []struct {
+		name string
+		f    Filter
+		mask uint32
+		want bool
+	}{
+		{"zero mask hits zero values", Filter{}, 0, true},
+		{"zero mask hits any category", Filter{Category: enemy}, 0, true},
+		{"mask includes the category", Filter{Category: enemy}, enemy | player, true},
+		{"mask includes the default category", Filter{}, player, true},
+		{"mask excludes the category", Filter{Category: enemy}, player, false},
+		{"sensor is skipped", Filter{Sensor: true}, 0, false},
+		{"sensor is skipped even if matched", Filter{Category: enemy, Sensor: true}, enemy, false},
+		{"collide none is still queried", Filter{CollideNone: true}, 0, true},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Hits(tt.mask); got != tt.want {
+			t.Errorf("%s: Hits(%#x) = %v, want %v", tt.name, tt.mask, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,591 @@
+package collision
+
+import (
+	"math"
+	"math/bits"
+	"reflect"
+	"slices"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 判定に使う数値の精度
+type Precision int
+
+const (
+	PrecisionFloat Precision = 0 // float64で判定する(既定)
+	PrecisionFixed Precision = 1 // 固定小数点数で判定する。WASMとネイティブのように環境が違っても結果が変わらない
+)
+
+// 今の精度と、PrecisionFixedにする前の判定関数の登録
+var (
+	precision       Precision
+	savedPairTests  map[pairKey]func(a, b Tester) bool
+	savedPointTests map[reflect.Type]func(x, y float64, c Tester) bool
+)
+
+// 多角形・円・複合形状の判定に使う精度を切り替えて、切り替える前の精度を返す
+// PrecisionFixedでは座標や角度を整数に丸めてから整数演算だけで判定するので、同じ入力なら常に同じ結果になる
+// 座標は±65536ピクセルまで。円は楕円になる場合、カプセルなどの形状は今まで通りfloat64で判定する
+//
+// 判定関数の登録を差し替えるので、プロセス全体の設定になる。判定中に呼んではいけない
+// PrecisionFloatに戻すと、RegisterPairTestなどで登録していた判定関数も切り替える前の状態に戻る
+// 一時的に切り替える場合は、返した精度で元に戻す
+func SetPrecision(p Precision) Precision {
+	prev := precision
+	if p != PrecisionFixed {
+		p = PrecisionFloat
+	}
+	if p == prev {
+		return prev
+	}
+	precision = p
+
+	if p == PrecisionFixed {
+		savedPairTests = map[pairKey]func(a, b Tester) bool{}
+		for _, k := range fixedPairKeys() {
+			if f, found := pairTests[k]; found {
+				savedPairTests[k] = f
+			}
+		}
+		savedPointTests = map[reflect.Type]func(x, y float64, c Tester) bool{}
+		for _, t := range fixedPointTypes() {
+			if f, found := pointTests[t]; found {
+				savedPointTests[t] = f
+			}
+		}
+
+		RegisterPairTest(TestPolygonPolygonFixed)
+		RegisterPairTest(TestCirclePolygonFixed)
+		RegisterPairTest(TestCircleCircleFixed)
+		RegisterPairTest(testPolygonCompositFixed)
+		RegisterPairTest(testCircleCompositFixed)
+		RegisterPairTest(testCompositCompositFixed)
+		RegisterPointTest(TestPointPolygonFixed)
+		RegisterPointTest(TestPointCircleFixed)
+		return prev
+	}
+
+	// 差し替えた組み合わせを切り替える前の登録に戻す
+	for _, k := range fixedPairKeys() {
+		if f, found := savedPairTests[k]; found {
+			pairTests[k] = f
+		} else {
+			delete(pairTests, k)
+		}
+	}
+	for _, t := range fixedPointTypes() {
+		if f, found := savedPointTests[t]; found {
+			pointTests[t] = f
+		} else {
+			delete(pointTests, t)
+		}
+	}
+	savedPairTests = nil
+	savedPointTests = nil
+	return prev
+}
+
+// PrecisionFixedで差し替える組み合わせ
+func fixedPairKeys() []pairKey {
+	po := reflect.TypeFor[*Polygon]()
+	ci := reflect.TypeFor[*Circle]()
+	co := reflect.TypeFor[*Composit]()
+	return []pairKey{{po, po}, {ci, po}, {ci, ci}, {po, co}, {ci, co}, {co, co}}
+}
+
+func fixedPointTypes() []reflect.Type {
+	return []reflect.Type{reflect.TypeFor[*Polygon](), reflect.TypeFor[*Circle]()}
+}
+
+// 固定小数点数(1/4096単位)
+type Fixed int64
+
+const (
+	fixedBits = 12
+	fixedOne  = 1 << fixedBits
+)
+
+// 一番近い固定小数点数に丸める
+func ToFixed(f float64) Fixed {
+	return Fixed(math.Round(f * fixedOne))
+}
+
+func (f Fixed) Float() float64 {
+	return float64(f) / fixedOne
+}
+
+func (f Fixed) Mul(o Fixed) Fixed {
+	return Fixed((int64(f)*int64(o) + fixedOne/2) >> fixedBits)
+}
+
+// 固定小数点数のベクトル
+type FixedVec struct {
+	X, Y Fixed
+}
+
+func ToFixedVec(v gmath.Vec) FixedVec {
+	return FixedVec{X: ToFixed(v.X), Y: ToFixed(v.Y)}
+}
+
+func (v FixedVec) Vec() gmath.Vec {
+	return gmath.Vec{X: v.X.Float(), Y: v.Y.Float()}
+}
+
+func (v FixedVec) Add(o FixedVec) FixedVec {
+	return FixedVec{X: v.X + o.X, Y: v.Y + o.Y}
+}
+
+func (v FixedVec) Sub(o FixedVec) FixedVec {
+	return FixedVec{X: v.X - o.X, Y: v.Y - o.Y}
+}
+
+// 内積(1/4096^2単位)
+func (v FixedVec) Dot(o FixedVec) int64 {
+	return int64(v.X)*int64(o.X) + int64(v.Y)*int64(o.Y)
+}
+
+// 外積(1/4096^2単位)
+// vから見てoが右回りの側にあれば正になる
+func (v FixedVec) Cross(o FixedVec) int64 {
+	return int64(v.X)*int64(o.Y) - int64(o.X)*int64(v.Y)
+}
+
+// 角度の計算に使う2^30倍の整数
+const (
+	angleBits   = 30
+	angleOne    = 1 << angleBits
+	angleTwoPi  = 6746518852
+	angleHalfPi = 1686629713
+)
+
+// 回転角度の正弦と余弦(2^30倍の整数)
+// 角度を整数にしてからテイラー展開するので、環境によって結果が変わらない
+func fixedSincos(r gmath.Rad) (int64, int64) {
+	// math.Modは誤差無く余りを求める
+	return angleSincos(int64(math.Round(math.Mod(float64(r), 2*math.Pi) * angleOne)))
+}
+
+func angleSincos(a int64) (int64, int64) {
+	// 0〜2πにしてから、±π/4の範囲と90度単位の回転に分ける
+	a %= angleTwoPi
+	if a < 0 {
+		a += angleTwoPi
+	}
+	n := (a + angleHalfPi/2) / angleHalfPi
+	x := a - n*angleHalfPi
+
+	x2 := (x * x) >> angleBits
+	sin, cos := x, int64(angleOne)
+	ts, tc := x, int64(angleOne)
+	for k := int64(1); k <= 6; k++ {
+		ts = -((ts * x2) >> angleBits) / ((2 * k) * (2*k + 1))
+		tc = -((tc * x2) >> angleBits) / ((2*k - 1) * (2 * k))
+		sin += ts
+		cos += tc
+	}
+
+	switch n % 4 {
+	case 1:
+		return cos, -sin
+	case 2:
+		return -sin, -cos
+	case 3:
+		return -cos, sin
+	}
+	return sin, cos
+}
+
+// 2^30倍の正弦と余弦で回転する
+func rotateFixed(v FixedVec, sin, cos int64) FixedVec {
+	round := func(x int64) Fixed {
+		return Fixed((x + angleOne/2) >> angleBits)
+	}
+	return FixedVec{
+		X: round(int64(v.X)*cos - int64(v.Y)*sin),
+		Y: round(int64(v.X)*sin + int64(v.Y)*cos),
+	}
+}
+
+// 頂点集合を固定小数点数のグローバル座標に変換する(右回り)
+// updateCacheと同じ順番で変換する
+func (p *Polygon) fixedVertices() []FixedVec {
+	s := ScaleOf(p.Scale)
+	sx, sy := ToFixed(s.X), ToFixed(s.Y)
+	sin, cos := fixedSincos(p.Rad)
+	origin := ToFixedVec(p.Origin)
+	pos := ToFixedVec(p.Pos)
+
+	vs := make([]FixedVec, 0, len(p.Vertices))
+	for _, v := range p.Vertices {
+		l := ToFixedVec(v).Sub(origin)
+		l = FixedVec{X: l.X.Mul(sx), Y: l.Y.Mul(sy)}
+		vs = append(vs, rotateFixed(l, sin, cos).Add(origin).Add(pos))
+	}
+
+	if s.X*s.Y < 0 {
+		slices.Reverse(vs)
+	}
+	return vs
+}
+
+// 点と凸型多角形の判定(固定小数点数)
+func TestPointPolygonFixed(x, y float64, p *Polygon) bool {
+	if len(p.Vertices) < 3 {
+		return false
+	}
+	return pointInFixedPolygon(ToFixedVec(gmath.Vec{X: x, Y: y}), p.fixedVertices())
+}
+
+// 点と円の判定(固定小数点数)
+func TestPointCircleFixed(x, y float64, c *Circle) bool {
+	d := ToFixedVec(gmath.Vec{X: x, Y: y}).Sub(ToFixedVec(c.Pos))
+	r := ToFixed(c.Radius)
+	return d.Dot(d) < int64(r)*int64(r)
+}
+
+// 円同士の判定(固定小数点数)
+func TestCircleCircleFixed(c1 *Circle, c2 *Circle) bool {
+	d := ToFixedVec(c2.Pos).Sub(ToFixedVec(c1.Pos))
+	r := ToFixed(c1.Radius) + ToFixed(c2.Radius)
+	return d.Dot(d) < int64(r)*int64(r)
+}
+
+// 円と凸型多角形の判定(固定小数点数)
+func TestCirclePolygonFixed(c *Circle, p *Polygon) bool {
+	if len(p.Vertices) < 3 {
+		return false
+	}
+	return circleInFixedPolygon(ToFixedVec(c.Pos), ToFixed(c.Radius), p.fixedVertices())
+}
+
+// 凸型多角形同士の判定(固定小数点数のSAT)
+func TestPolygonPolygonFixed(c1 *Polygon, c2 *Polygon) bool {
+	if len(c1.Vertices) < 3 || len(c2.Vertices) < 3 {
+		return false
+	}
+	return fixedPolygonsOverlap(c1.fixedVertices(), c2.fixedVertices())
+}
+
+// 点が右回りの凸型多角形の内側または境界上にあるか
+func pointInFixedPolygon(p FixedVec, vs []FixedVec) bool {
+	for i := range vs {
+		if p.Sub(vs[i]).Cross(vs[(i+1)%len(vs)].Sub(vs[i])) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func circleInFixedPolygon(c FixedVec, r Fixed, vs []FixedVec) bool {
+	if len(vs) == 0 {
+		return false
+	}
+	if len(vs) >= 3 && pointInFixedPolygon(c, vs) {
+		return true
+	}
+
+	// いずれかのエッジまでの距離が半径より近い
+	rr := int64(r) * int64(r)
+	for i := range vs {
+		a := vs[i]
+		b := vs[(i+1)%len(vs)]
+		ab := b.Sub(a)
+		ap := c.Sub(a)
+		t := ap.Dot(ab)
+		l := ab.Dot(ab)
+
+		switch {
+		case t <= 0 || l == 0:
+			if ap.Dot(ap) < rr {
+				return true
+			}
+		case t >= l:
+			if bp := c.Sub(b); bp.Dot(bp) < rr {
+				return true
+			}
+		default:
+			// 直線までの距離の2乗はcross^2/lなので、両辺にlを掛けて比べる
+			cr := absInt(ap.Cross(ab))
+			if lessProduct(cr, cr, uint64(rr), uint64(l)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 各エッジの外向き法線方向に射影して範囲が重なっているかを調べる
+func fixedPolygonsOverlap(r1, r2 []FixedVec) bool {
+	if len(r1) == 0 || len(r2) == 0 {
+		return false
+	}
+
+	for _, r := range [2][]FixedVec{r1, r2} {
+		for i := range r {
+			e := r[(i+1)%len(r)].Sub(r[i])
+			min1, max1 := projectFixed(r1, e)
+			min2, max2 := projectFixed(r2, e)
+			if min1 > max2 || max1 < min2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func projectFixed(r []FixedVec, e FixedVec) (int64, int64) {
+	lo, hi := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, v := range r {
+		s := v.Cross(e)
+		lo = min(lo, s)
+		hi = max(hi, s)
+	}
+	return lo, hi
+}
+
+func absInt(x int64) uint64 {
+	if x < 0 {
+		return uint64(-x)
+	}
+	return uint64(x)
+}
+
+// a*b < c*dを桁あふれさせずに比べる
+func lessProduct(a, b, c, d uint64) bool {
+	h1, l1 := bits.Mul64(a, b)
+	h2, l2 := bits.Mul64(c, d)
+	return h1 < h2 || (h1 == h2 && l1 < l2)
+}
+
+// a*b/cを桁あふれさせずに求める(0方向に丸める)
+// 結果がint64に収まらなければならない
+func mulDiv(a, b, c int64) int64 {
+	hi, lo := bits.Mul64(absInt(a), absInt(b))
+	q, _ := bits.Div64(hi, lo, absInt(c))
+	if (a < 0) != (b < 0) != (c < 0) {
+		return -int64(q)
+	}
+	return int64(q)
+}
+
+func testPolygonCompositFixed(p *Polygon, co *Composit) bool {
+	return testCompositFixed(p, co)
+}
+
+func testCircleCompositFixed(c *Circle, co *Composit) bool {
+	return testCompositFixed(c, co)
+}
+
+func testCompositCompositFixed(c1 *Composit, c2 *Composit) bool {
+	return testCompositFixed(c1, c2)
+}
+
+// 形状と複合形状の判定(固定小数点数)
+// Orは子要素ごとに判定し、それ以外は固定小数点数で切り取った断片で判定する
+// 円は多角形で近似するので、Andの共通部分もfloat64の場合と違って近似になる
+func testCompositFixed(o Tester, co *Composit) bool {
+	if co.Operator == CompositOr {
+		for _, d := range co.Collisions {
+			if o.Test(d) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, p := range fixedPieces(co) {
+		if testFixedPiece(o, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// 形状と断片の判定
+func testFixedPiece(o Tester, piece []FixedVec) bool {
+	switch v := scaledShape(o).(type) {
+	case *Polygon:
+		if len(v.Vertices) < 3 {
+			return false
+		}
+		return fixedPolygonsOverlap(v.fixedVertices(), piece)
+	case *Circle:
+		return circleInFixedPolygon(ToFixedVec(v.Pos), ToFixed(v.Radius), piece)
+	case *Composit:
+		if v.Operator == CompositOr {
+			for _, d := range v.Collisions {
+				if testFixedPiece(d, piece) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, q := range fixedPieces(v) {
+			if fixedPolygonsOverlap(q, piece) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 固定小数点数で扱えない形状はfloat64で判定する
+	vs := make([]gmath.Vec, 0, len(piece))
+	for _, p := range piece {
+		vs = append(vs, p.Vec())
+	}
+	return o.Test(&Polygon{Vertices: vs})
+}
+
+// 形状を固定小数点数の凸多角形の集合(右回り)に分解する
+// Piecesと同じ組み合わせ方で、固定小数点数で扱えない形状はPiecesの結果を丸める
+func fixedPieces(c Tester) [][]FixedVec {
+	switch d := scaledShape(c).(type) {
+	case *Polygon:
+		if len(d.Vertices) < 3 {
+			return nil
+		}
+		return [][]FixedVec{d.fixedVertices()}
+	case *Circle:
+		return [][]FixedVec{fixedCircleVertices(ToFixedVec(d.Pos), ToFixed(d.Radius), circleSegments)}
+	case *Composit:
+		var result [][]FixedVec
+		for i, e := range d.Collisions {
+			p := fixedPieces(e)
+			if i == 0 {
+				result = p
+				continue
+			}
+
+			switch d.Operator {
+			case CompositOr:
+				result = append(result, p...)
+			case CompositAnd:
+				result = intersectFixedPieces(result, p)
+			case CompositNot:
+				result = subtractFixedPieces(result, p)
+			case CompositXor:
+				result = append(subtractFixedPieces(result, p), subtractFixedPieces(p, result)...)
+			}
+		}
+		return result
+	}
+
+	var result [][]FixedVec
+	for _, p := range Pieces(c) {
+		vs := make([]FixedVec, 0, len(p))
+		for _, v := range p {
+			vs = append(vs, ToFixedVec(v))
+		}
+		result = append(result, vs)
+	}
+	return result
+}
+
+// circleVerticesと同じ並びの円周上の頂点
+func fixedCircleVertices(pos FixedVec, r Fixed, n int) []FixedVec {
+	vs := make([]FixedVec, 0, n)
+	for i := 0; i < n; i++ {
+		sin, cos := angleSincos(angleTwoPi * int64(i) / int64(n))
+		vs = append(vs, rotateFixed(FixedVec{X: r}, sin, cos).Add(pos))
+	}
+	return vs
+}
+
+func intersectFixedPieces(a, b [][]FixedVec) [][]FixedVec {
+	var result [][]FixedVec
+	for _, p := range a {
+		for _, q := range b {
+			if r := intersectFixedConvex(p, q); r != nil {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
+
+func subtractFixedPieces(a, b [][]FixedVec) [][]FixedVec {
+	result := a
+	for _, q := range b {
+		next := make([][]FixedVec, 0, len(result))
+		for _, p := range result {
+			next = append(next, subtractFixedConvex(p, q)...)
+		}
+		result = next
+	}
+	return result
+}
+
+func intersectFixedConvex(p, q []FixedVec) []FixedVec {
+	r := p
+	for i := range q {
+		r = clipFixedHalfPlane(r, q[i], q[(i+1)%len(q)], true)
+		if r == nil {
+			return nil
+		}
+	}
+	return r
+}
+
+func subtractFixedConvex(p, q []FixedVec) [][]FixedVec {
+	var result [][]FixedVec
+	rest := p
+	for i := range q {
+		a := q[i]
+		b := q[(i+1)%len(q)]
+		if out := clipFixedHalfPlane(rest, a, b, false); out != nil {
+			result = append(result, out)
+		}
+		rest = clipFixedHalfPlane(rest, a, b, true)
+		if rest == nil {
+			break
+		}
+	}
+	return result
+}
+
+// clipHalfPlaneの固定小数点数版
+// 面積の無い断片はnilになる
+func clipFixedHalfPlane(p []FixedVec, a, b FixedVec, inside bool) []FixedVec {
+	e := b.Sub(a)
+	side := func(v FixedVec) int64 {
+		s := v.Sub(a).Cross(e)
+		if inside {
+			return -s
+		}
+		return s
+	}
+
+	r := make([]FixedVec, 0, len(p)+1)
+	for i := range p {
+		v1 := p[i]
+		v2 := p[(i+1)%len(p)]
+		s1 := side(v1)
+		s2 := side(v2)
+		if s1 >= 0 {
+			r = append(r, v1)
+		}
+		if (s1 > 0 && s2 < 0) || (s1 < 0 && s2 > 0) {
+			d := v2.Sub(v1)
+			r = append(r, v1.Add(FixedVec{
+				X: Fixed(mulDiv(int64(d.X), s1, s1-s2)),
+				Y: Fixed(mulDiv(int64(d.Y), s1, s1-s2)),
+			}))
+		}
+	}
+
+	if len(r) < 3 || fixedArea2(r) <= 0 {
+		return nil
+	}
+	return r
+}
+
+// 符号付き面積の2倍(右回りで正)
+// 桁あふれしないように最初の頂点からの相対座標で求める
+func fixedArea2(vs []FixedVec) int64 {
+	a := int64(0)
+	for i := 1; i < len(vs)-1; i++ {
+		a += vs[i].Sub(vs[0]).Cross(vs[i+1].Sub(vs[0]))
+	}
+	return a
+}
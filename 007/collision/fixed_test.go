@@ -0,0 +1,245 @@
+package collision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 固定小数点数の判定に切り替えて、テストの終わりに元に戻す
+func useFixed(t *testing.T) {
+	prev := SetPrecision(PrecisionFixed)
+	t.Cleanup(func() { SetPrecision(prev) })
+}
+
+// 環境によって変わらない値を記録しておき、同じ値になることを確かめる
+func TestAngleSincos(t *testing.T) {
+	tests := []struct {
+		rad      float64
+		sin, cos int64
+	}{
+		{0, 0, 1073741824},
+		{math.Pi / 6, 536870912, 929887698},
+		{math.Pi / 4, 759250125, 759250125},
+		{math.Pi / 2, 1073741824, 0},
+		{2, 976350678, -446834264},
+		{math.Pi, 0, -1073741824},
+		{-math.Pi / 3, -929887698, 536870912},
+		{5, -1029637100, 304579953},
+		{100, -543705966, 925907840},
+	}
+	for _, tt := range tests {
+		sin, cos := fixedSincos(gmath.Rad(tt.rad))
+		if sin != tt.sin || cos != tt.cos {
+			t.Errorf("fixedSincos(%v) = %d, %d, want %d, %d", tt.rad, sin, cos, tt.sin, tt.cos)
+		}
+
+		// 記録した値自体もfloat64の値とほぼ同じ
+		if d := math.Abs(float64(sin) - math.Sin(tt.rad)*angleOne); d > 2 {
+			t.Errorf("sin(%v) is off by %v", tt.rad, d)
+		}
+		if d := math.Abs(float64(cos) - math.Cos(tt.rad)*angleOne); d > 2 {
+			t.Errorf("cos(%v) is off by %v", tt.rad, d)
+		}
+	}
+}
+
+func TestRotateFixed(t *testing.T) {
+	sin, cos := fixedSincos(0.5)
+	tests := []struct {
+		v, want FixedVec
+	}{
+		{FixedVec{X: 4096, Y: 0}, FixedVec{X: 3595, Y: 1964}},
+		{FixedVec{X: 40960, Y: -12288}, FixedVec{X: 41837, Y: 8854}},
+		{FixedVec{X: -123456, Y: 7}, FixedVec{X: -108346, Y: -59182}},
+	}
+	for _, tt := range tests {
+		if got := rotateFixed(tt.v, sin, cos); got != tt.want {
+			t.Errorf("rotateFixed(%v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+
+	// 回転・拡大・回転原点を含めた頂点の変換。片方の軸の反転で並びが右回りに戻る
+	p := &Polygon{
+		Pos:      gmath.Vec{X: 100.3, Y: -20.7},
+		Rad:      1.1,
+		Scale:    gmath.Vec{X: 2, Y: -0.5},
+		Origin:   gmath.Vec{X: 5, Y: 5},
+		Vertices: []gmath.Vec{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}},
+	}
+	want := []FixedVec{{X: 421856, Y: -105456}, {X: 459014, Y: -32448}, {X: 440762, Y: -23158}, {X: 403604, Y: -96166}}
+	got := p.fixedVertices()
+	if len(got) != len(want) {
+		t.Fatalf("fixedVertices() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("fixedVertices() = %v, want %v", got, want)
+		}
+	}
+	if fixedArea2(got) <= 0 {
+		t.Errorf("fixedVertices() is not right-wound: %v", got)
+	}
+}
+
+// 円を取り除いたリングの断片は重ならず、面積は円を近似した多角形の差になる
+func TestFixedPieces(t *testing.T) {
+	ring := &Composit{
+		Operator: CompositNot,
+		Collisions: []Tester{
+			&Circle{Pos: gmath.Vec{X: 50, Y: 50}, Radius: 30},
+			&Circle{Pos: gmath.Vec{X: 50, Y: 50}, Radius: 20},
+		},
+	}
+	pieces := fixedPieces(ring)
+	total := int64(0)
+	for _, p := range pieces {
+		if a := fixedArea2(p); a <= 0 {
+			t.Errorf("piece %v has area %d", p, a)
+		}
+		total += fixedArea2(p)
+	}
+	if len(pieces) != 32 || total != 52369254031 {
+		t.Errorf("got %d pieces with area %d, want 32 pieces with area 52369254031", len(pieces), total)
+	}
+
+	// 32角形の面積はπr^2の約0.99倍
+	area := float64(total) / 2 / fixedOne / fixedOne
+	if want := math.Pi * (30*30 - 20*20); area < want*0.98 || area > want {
+		t.Errorf("ring area = %v, want about %v", area, want)
+	}
+}
+
+func squarePolygon(x, y, size float64) *Polygon {
+	return &Polygon{Pos: gmath.Vec{X: x, Y: y}, Vertices: square(0, 0, size)}
+}
+
+// 記録した組み合わせの判定結果
+// どちらの精度でも同じ結果になる
+func TestFixedScenarios(t *testing.T) {
+	t.Run("float", testScenarios)
+	t.Run("fixed", func(t *testing.T) {
+		useFixed(t)
+		testScenarios(t)
+	})
+}
+
+func testScenarios(t *testing.T) {
+	ring := func() *Composit {
+		return &Composit{
+			Operator: CompositNot,
+			Collisions: []Tester{
+				&Circle{Pos: gmath.Vec{X: 50, Y: 50}, Radius: 30},
+				&Circle{Pos: gmath.Vec{X: 50, Y: 50}, Radius: 20},
+			},
+		}
+	}
+	lens := &Composit{
+		Operator: CompositAnd,
+		Collisions: []Tester{
+			&Circle{Pos: gmath.Vec{X: 0, Y: 0}, Radius: 20},
+			&Circle{Pos: gmath.Vec{X: 30, Y: 0}, Radius: 20},
+		},
+	}
+	xor := &Composit{
+		Operator:   CompositXor,
+		Collisions: []Tester{squarePolygon(0, 0, 20), squarePolygon(10, 10, 20)},
+	}
+	line := &Polygon{Pos: gmath.Vec{X: 10, Y: 10}, Vertices: []gmath.Vec{{X: -20, Y: 0}, {X: 20, Y: 0}}}
+	rotated := squarePolygon(0, 0, 20)
+	rotated.Origin = gmath.Vec{X: 10, Y: 10}
+	rotated.Rad = math.Pi / 4
+
+	tests := []struct {
+		name string
+		a, b Tester
+		want bool
+	}{
+		{"overlapping squares", squarePolygon(0, 0, 20), squarePolygon(15, 15, 20), true},
+		{"separated squares", squarePolygon(0, 0, 20), squarePolygon(20.5, 0, 20), false},
+		{"squares sharing an edge", squarePolygon(0, 0, 20), squarePolygon(20, 0, 20), true},
+		{"rotated square past the corner", rotated, squarePolygon(24.2, 24.2, 10), false},
+		{"rotated square reaching the side", rotated, squarePolygon(24.1, 5, 10), true},
+		{"circle near a corner", &Circle{Pos: gmath.Vec{X: 27.1, Y: 27.1}, Radius: 10}, squarePolygon(0, 0, 20), false},
+		{"circle over a corner", &Circle{Pos: gmath.Vec{X: 27, Y: 27}, Radius: 10}, squarePolygon(0, 0, 20), true},
+		{"circle inside a polygon", &Circle{Pos: gmath.Vec{X: 10, Y: 10}, Radius: 2}, squarePolygon(0, 0, 20), true},
+		{"touching circles", &Circle{Radius: 10}, &Circle{Pos: gmath.Vec{X: 20}, Radius: 10}, false},
+		{"overlapping circles", &Circle{Radius: 10}, &Circle{Pos: gmath.Vec{X: 19.999}, Radius: 10}, true},
+		{"circle in the ring hole", &Circle{Pos: gmath.Vec{X: 50, Y: 50}, Radius: 15}, ring(), false},
+		{"circle across the ring", &Circle{Pos: gmath.Vec{X: 50, Y: 25}, Radius: 3}, ring(), true},
+		{"polygon in the ring hole", squarePolygon(40, 40, 20), ring(), false},
+		{"polygon outside the lens", squarePolygon(14, 14, 5), lens, false},
+		{"polygon in the lens", squarePolygon(14, -2, 4), lens, true},
+		{"polygon in the xor overlap", squarePolygon(15, 15, 2), xor, false},
+		{"polygon in the xor rest", squarePolygon(2, 2, 2), xor, true},
+		{"ring and lens", ring(), &Composit{Operator: CompositOr, Collisions: []Tester{lens}}, false},
+		{"ring and xor", ring(), xor, true},
+		{"2-vertex polygon across a polygon", line, squarePolygon(0, 0, 20), false},
+		{"2-vertex polygon across a circle", line, &Circle{Pos: gmath.Vec{X: 10, Y: 10}, Radius: 5}, false},
+		{"2-vertex polygon across the xor", line, xor, false},
+	}
+	for _, tt := range tests {
+		if got := TestShapes(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: TestShapes = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := TestShapes(tt.b, tt.a); got != tt.want {
+			t.Errorf("%s: swapped TestShapes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	points := []struct {
+		name string
+		x, y float64
+		c    Tester
+		want bool
+	}{
+		{"point on a polygon edge", 20, 10, squarePolygon(0, 0, 20), true},
+		{"point just outside a polygon", 20.001, 10, squarePolygon(0, 0, 20), false},
+		{"point on a circle", 10, 0, &Circle{Radius: 10}, false},
+		{"point in a circle", 9.999, 0, &Circle{Radius: 10}, true},
+		{"point in the ring hole", 50, 50, ring(), false},
+		{"point in the ring", 50, 25, ring(), true},
+		{"point on a 2-vertex polygon", 10, 10, line, false},
+	}
+	for _, tt := range points {
+		if got := TestPoint(tt.x, tt.y, tt.c); got != tt.want {
+			t.Errorf("%s: TestPoint = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 元に戻すと、切り替える前に登録していた判定関数も戻る
+func TestSetPrecisionRestores(t *testing.T) {
+	calls := 0
+	custom := func(p *Polygon, co *Composit) bool {
+		calls++
+		return false
+	}
+	RegisterPairTest(custom)
+	t.Cleanup(func() { delete(pairTests, fixedPairKeys()[3]) })
+
+	a, b := squarePolygon(0, 0, 10), &Composit{Collisions: []Tester{squarePolygon(5, 5, 10)}}
+
+	if prev := SetPrecision(PrecisionFixed); prev != PrecisionFloat {
+		t.Fatalf("SetPrecision returned %v, want PrecisionFloat", prev)
+	}
+	if !a.Test(b) || calls != 0 {
+		t.Fatalf("fixed precision did not replace the Polygon/Composit test")
+	}
+	if prev := SetPrecision(PrecisionFixed); prev != PrecisionFixed {
+		t.Fatalf("SetPrecision returned %v, want PrecisionFixed", prev)
+	}
+	if prev := SetPrecision(PrecisionFloat); prev != PrecisionFixed {
+		t.Fatalf("SetPrecision returned %v, want PrecisionFixed", prev)
+	}
+	if a.Test(b) || calls != 1 {
+		t.Errorf("registered Polygon/Composit test was not restored")
+	}
+	if _, found := pairTests[fixedPairKeys()[4]]; found {
+		t.Errorf("Circle/Composit test is still registered")
+	}
+	if f := pointTests[fixedPointTypes()[0]]; f == nil || !f(5, 5, squarePolygon(0, 0, 10)) {
+		t.Errorf("Polygon point test was not restored")
+	}
+}
@@ -0,0 +1,382 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// サポート関数を持つ凸形状のインターフェース
+type Convex interface {
+	Tester
+	Support(d gmath.Vec) gmath.Vec // d方向に一番遠い点(グローバル座標)
+}
+
+func (p *Polygon) Support(d gmath.Vec) gmath.Vec {
+	return supportPoints(p.worldVertices(), d)
+}
+
+func (c *Circle) Support(d gmath.Vec) gmath.Vec {
+	if s := scaledShape(c); s != Tester(c) {
+		return s.(Convex).Support(d)
+	}
+	return c.Pos.Add(d.Normalized().Mulf(c.Radius))
+}
+
+// 点集合のうちd方向に一番遠い点
+func supportPoints(vs []gmath.Vec, d gmath.Vec) gmath.Vec {
+	result := vs[0]
+	max := math.Inf(-1)
+	for _, v := range vs {
+		if s := v.Dot(d); s > max {
+			max = s
+			result = v
+		}
+	}
+	return result
+}
+
+// ミンコフスキー差a-bのサポート点
+func supportDiff(a, b Convex, d gmath.Vec) gmath.Vec {
+	return a.Support(d).Sub(b.Support(d.Neg()))
+}
+
+// 凸形状同士の判定(GJK)
+// ミンコフスキー差が原点を含んでいれば重なっている
+func TestConvexConvex(a, b Convex) bool {
+	d := gmath.Vec{X: 1, Y: 0}
+	simplex := []gmath.Vec{supportDiff(a, b, d)}
+	d = simplex[0].Neg()
+
+	for i := 0; i < 64; i++ {
+		// 原点がシンプレックス上にある
+		if d.IsZero() {
+			return true
+		}
+
+		p := supportDiff(a, b, d)
+
+		// d方向に原点を越えられない場合は重なっていない
+		if p.Dot(d) < 0 {
+			return false
+		}
+
+		simplex = append(simplex, p)
+		if nextSimplex(&simplex, &d) {
+			return true
+		}
+	}
+
+	// 収束しない場合は外周の多角形でSATの判定をする
+	return TestPolygonPolygon(convexPolygon(a), convexPolygon(b))
+}
+
+// 凸形状を同じ形(曲線は近似)の凸型多角形にする
+func convexPolygon(c Convex) *Polygon {
+	if p, ok := c.(*Polygon); ok {
+		return p
+	}
+	if ps := Pieces(c); len(ps) == 1 {
+		return &Polygon{Vertices: ps[0]}
+	}
+	return &Polygon{Vertices: supportOutline(c)}
+}
+
+// シンプレックスを原点に近い部分に絞り込み、次の探索方向を決める
+// 原点を含んだ場合はtrueを返す
+func nextSimplex(simplex *[]gmath.Vec, d *gmath.Vec) bool {
+	s := *simplex
+	a := s[len(s)-1]
+	ao := a.Neg()
+
+	if len(s) == 2 {
+		ab := s[0].Sub(a)
+		if ab.Dot(ao) > 0 {
+			*d = tripleProduct(ab, ao, ab)
+		} else {
+			*simplex = []gmath.Vec{a}
+			*d = ao
+		}
+		return false
+	}
+
+	b := s[1]
+	c := s[0]
+	ab := b.Sub(a)
+	ac := c.Sub(a)
+	abPerp := tripleProduct(ac, ab, ab)
+	acPerp := tripleProduct(ab, ac, ac)
+
+	if abPerp.Dot(ao) > 0 {
+		*simplex = []gmath.Vec{b, a}
+		*d = abPerp
+		return false
+	}
+	if acPerp.Dot(ao) > 0 {
+		*simplex = []gmath.Vec{c, a}
+		*d = acPerp
+		return false
+	}
+	return true
+}
+
+// ベクトル三重積(a×b)×c
+// aとbの平面上で、cと垂直なベクトルになる
+func tripleProduct(a, b, c gmath.Vec) gmath.Vec {
+	return b.Mulf(a.Dot(c)).Sub(a.Mulf(b.Dot(c)))
+}
+
+// 凸形状同士の距離情報
+type DistanceResult struct {
+	Distance float64   // 最短距離
+	PointA   gmath.Vec // a側の最近点
+	PointB   gmath.Vec // b側の最近点
+}
+
+// 最近点を求めるためのシンプレックスの頂点
+type simplexVertex struct {
+	a gmath.Vec // aのサポート点
+	b gmath.Vec // bのサポート点
+	p gmath.Vec // a-b
+	w float64   // 原点に一番近い点を表す重み
+}
+
+func newSimplexVertex(a, b Convex, d gmath.Vec) simplexVertex {
+	sa := a.Support(d)
+	sb := b.Support(d.Neg())
+	return simplexVertex{a: sa, b: sb, p: sa.Sub(sb), w: 1}
+}
+
+// 凸形状同士の最短距離と最近点を求める(GJK)
+// 重なっている場合はfalseを返す
+func ConvexDistance(a, b Convex) (DistanceResult, bool) {
+	simplex := []simplexVertex{newSimplexVertex(a, b, gmath.Vec{X: 1, Y: 0})}
+
+	for i := 0; i < 64; i++ {
+		var v gmath.Vec
+		simplex, v = closestSimplex(simplex)
+
+		// 原点を含んでいる
+		if len(simplex) == 3 || v.LenSquared() < pieceEpsilon {
+			return DistanceResult{}, false
+		}
+
+		w := newSimplexVertex(a, b, v.Neg())
+
+		// これ以上原点に近づけない
+		if v.LenSquared()-v.Dot(w.p) <= 1e-10*v.LenSquared() || containsVertex(simplex, w.p) {
+			break
+		}
+		simplex = append(simplex, w)
+	}
+
+	var pa, pb gmath.Vec
+	for _, s := range simplex {
+		pa = pa.Add(s.a.Mulf(s.w))
+		pb = pb.Add(s.b.Mulf(s.w))
+	}
+	return DistanceResult{Distance: pa.DistanceTo(pb), PointA: pa, PointB: pb}, true
+}
+
+func containsVertex(simplex []simplexVertex, p gmath.Vec) bool {
+	for _, s := range simplex {
+		if s.p.DistanceSquaredTo(p) < pieceEpsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// シンプレックス上で原点に一番近い点を求め、その点を表すのに必要な頂点だけを残す
+// 三角形が原点を含む場合は3頂点のまま返す
+func closestSimplex(s []simplexVertex) ([]simplexVertex, gmath.Vec) {
+	switch len(s) {
+	case 1:
+		s[0].w = 1
+		return s, s[0].p
+	case 2:
+		return closestSegment(s[0], s[1])
+	}
+
+	// 三角形の内側に原点があるか
+	a, b, c := s[0].p, s[1].p, s[2].p
+	d1 := turn(a, b, gmath.Vec{})
+	d2 := turn(b, c, gmath.Vec{})
+	d3 := turn(c, a, gmath.Vec{})
+	if (d1 >= 0 && d2 >= 0 && d3 >= 0) || (d1 <= 0 && d2 <= 0 && d3 <= 0) {
+		return s, gmath.Vec{}
+	}
+
+	// 一番近いエッジに絞る
+	var result []simplexVertex
+	var v gmath.Vec
+	best := math.Inf(1)
+	for _, e := range [][2]simplexVertex{{s[0], s[1]}, {s[1], s[2]}, {s[2], s[0]}} {
+		r, p := closestSegment(e[0], e[1])
+		if l := p.LenSquared(); l < best {
+			best = l
+			result = r
+			v = p
+		}
+	}
+	return result, v
+}
+
+// 線分上で原点に一番近い点
+func closestSegment(a, b simplexVertex) ([]simplexVertex, gmath.Vec) {
+	ab := b.p.Sub(a.p)
+	l := ab.LenSquared()
+	t := 0.0
+	if l > 0 {
+		t = gmath.Clamp(-a.p.Dot(ab)/l, 0, 1)
+	}
+
+	switch t {
+	case 0:
+		a.w = 1
+		return []simplexVertex{a}, a.p
+	case 1:
+		b.w = 1
+		return []simplexVertex{b}, b.p
+	}
+	a.w = 1 - t
+	b.w = t
+	return []simplexVertex{a, b}, a.p.Add(ab.Mulf(t))
+}
+
+// 重なっている凸形状同士のめり込みを求める(EPA)
+// 法線はaからbへ向かう方向で、bを法線方向にめり込み量だけ動かすと離れる
+func ConvexPenetration(a, b Convex) (Contact, bool) {
+	polytope, ok := gjkSimplex(a, b)
+	if !ok {
+		return Contact{}, false
+	}
+
+	for i := 0; i < 64; i++ {
+		// 原点に一番近いエッジを探す
+		idx := 0
+		dist := math.Inf(1)
+		var normal gmath.Vec
+		for j := range polytope {
+			p1 := polytope[j]
+			p2 := polytope[(j+1)%len(polytope)]
+			e := p2.Sub(p1)
+			n := gmath.Vec{X: e.Y, Y: -e.X}.Normalized()
+			if d := n.Dot(p1); d < dist {
+				dist = d
+				normal = n
+				idx = j
+			}
+		}
+
+		// そのエッジより外に広げられなければ終了
+		p := supportDiff(a, b, normal)
+		if p.Dot(normal)-dist < 1e-9 {
+			return Contact{
+				Normal: normal,
+				Depth:  dist,
+				Points: []gmath.Vec{b.Support(normal.Neg())},
+			}, true
+		}
+
+		polytope = append(polytope[:idx+1], append([]gmath.Vec{p}, polytope[idx+1:]...)...)
+	}
+
+	return Contact{}, false
+}
+
+// 原点を含む右回りの三角形をミンコフスキー差の中に作る
+func gjkSimplex(a, b Convex) ([]gmath.Vec, bool) {
+	d := gmath.Vec{X: 1, Y: 0}
+	simplex := []gmath.Vec{supportDiff(a, b, d)}
+	d = simplex[0].Neg()
+
+	for i := 0; i < 64; i++ {
+		if d.IsZero() {
+			break
+		}
+		p := supportDiff(a, b, d)
+		if p.Dot(d) < 0 {
+			return nil, false
+		}
+		simplex = append(simplex, p)
+		if nextSimplex(&simplex, &d) {
+			break
+		}
+	}
+
+	// 原点が辺上にあるなどで三角形になっていない場合は、垂直方向のサポート点で補う
+	for _, dir := range []gmath.Vec{{X: 0, Y: 1}, {X: 0, Y: -1}, {X: 1, Y: 0}, {X: -1, Y: 0}} {
+		if len(simplex) >= 3 && math.Abs(polygonArea(simplex)) > pieceEpsilon {
+			break
+		}
+		p := supportDiff(a, b, dir)
+		if !containsPoint(simplex, p) {
+			simplex = append(simplex, p)
+		}
+		if len(simplex) > 3 {
+			simplex = simplex[len(simplex)-3:]
+		}
+	}
+	if len(simplex) < 3 || math.Abs(polygonArea(simplex)) <= pieceEpsilon {
+		return nil, false
+	}
+
+	if polygonArea(simplex) < 0 {
+		simplex[0], simplex[1] = simplex[1], simplex[0]
+	}
+	return simplex, true
+}
+
+func containsPoint(vs []gmath.Vec, p gmath.Vec) bool {
+	for _, v := range vs {
+		if v.DistanceSquaredTo(p) < pieceEpsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// 形状同士の最短距離と最近点を求める
+// 複合形状はOrの場合は子要素、それ以外の場合は領域の断片のうち一番近いものとの距離になる
+// 重なっている場合はfalseを返す
+func ShapeDistance(a, b Tester) (DistanceResult, bool) {
+	var result DistanceResult
+	found := false
+	for _, ca := range convexList(a) {
+		for _, cb := range convexList(b) {
+			r, ok := ConvexDistance(ca, cb)
+			if !ok {
+				return DistanceResult{}, false
+			}
+			if !found || r.Distance < result.Distance {
+				result = r
+				found = true
+			}
+		}
+	}
+	return result, found
+}
+
+// 形状を凸形状の集合に分ける
+func convexList(c Tester) []Convex {
+	switch v := c.(type) {
+	case *Composit:
+		if v.Operator != CompositOr {
+			break
+		}
+		var result []Convex
+		for _, d := range v.Collisions {
+			result = append(result, convexList(d)...)
+		}
+		return result
+	case Convex:
+		return []Convex{v}
+	}
+
+	var result []Convex
+	for _, p := range pieceTesters(c) {
+		result = append(result, p.(Convex))
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package collision
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// ランダムな凸型多角形
+func randomConvex(r *rand.Rand, spread float64) *Polygon {
+	var points []gmath.Vec
+	for i := 0; i < 3+r.Intn(6); i++ {
+		points = append(points, gmath.Vec{X: r.Float64()*40 - 20, Y: r.Float64()*40 - 20})
+	}
+	return &Polygon{
+		Pos:      gmath.Vec{X: r.Float64() * spread, Y: r.Float64() * spread},
+		Rad:      gmath.Rad(r.Float64() * 2 * math.Pi),
+		Vertices: ConvexHull(points),
+	}
+}
+
+// GJK/EPAの結果をSATの結果と比べる
+func TestConvexMatchesSAT(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		a := randomConvex(r, 60)
+		b := randomConvex(r, 60)
+		if len(a.Vertices) < 3 || len(b.Vertices) < 3 {
+			continue
+		}
+
+		want := TestPolygonPolygon(a, b)
+		if got := TestConvexConvex(a, b); got != want {
+			t.Fatalf("pair %d: TestConvexConvex = %v, TestPolygonPolygon = %v", i, got, want)
+		}
+
+		d, separated := ConvexDistance(a, b)
+		if separated == want {
+			t.Fatalf("pair %d: ConvexDistance separated = %v, TestPolygonPolygon = %v", i, separated, want)
+		}
+		if separated {
+			// 最近点同士の距離が最短距離になる
+			if math.Abs(d.PointA.DistanceTo(d.PointB)-d.Distance) > 1e-6 {
+				t.Fatalf("pair %d: closest points are %v apart, distance %v", i, d.PointA.DistanceTo(d.PointB), d.Distance)
+			}
+
+			// 最短距離はエッジ同士の距離の最小値と同じ
+			ra, rb := a.worldVertices(), b.worldVertices()
+			want := math.Inf(1)
+			for x := 0; x < len(ra)-1; x++ {
+				for y := 0; y < len(rb)-1; y++ {
+					want = min(want, math.Sqrt(segmentDistanceSquared(ra[x], ra[x+1], rb[y], rb[y+1])))
+				}
+			}
+			if math.Abs(d.Distance-want) > 1e-6 {
+				t.Fatalf("pair %d: ConvexDistance = %v, want %v", i, d.Distance, want)
+			}
+			continue
+		}
+
+		sat, _ := CollidePolygonPolygon(a, b)
+		epa, ok := ConvexPenetration(a, b)
+		if !ok {
+			t.Fatalf("pair %d: ConvexPenetration failed on overlapping shapes", i)
+		}
+		if math.Abs(epa.Depth-sat.Depth) > 1e-6 {
+			t.Fatalf("pair %d: EPA depth = %v, SAT depth = %v", i, epa.Depth, sat.Depth)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package collision
+
+import (
+	"math"
+	"slices"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 点集合を囲む凸包の頂点を右回りで返す(Andrewのモノトーンチェーン)
+// 一直線上に並んだ頂点は含めない
+func ConvexHull(points []gmath.Vec) []gmath.Vec {
+	ps := slices.Clone(points)
+	slices.SortFunc(ps, func(a, b gmath.Vec) int {
+		if a.X != b.X {
+			return cmpFloat(a.X, b.X)
+		}
+		return cmpFloat(a.Y, b.Y)
+	})
+	ps = slices.CompactFunc(ps, func(a, b gmath.Vec) bool {
+		return a.DistanceSquaredTo(b) < pieceEpsilon
+	})
+	if len(ps) < 3 {
+		return ps
+	}
+
+	// 下側と上側をそれぞれ右回りに曲がる頂点だけ残して作る
+	rev := slices.Clone(ps)
+	slices.Reverse(rev)
+	hull := make([]gmath.Vec, 0, len(ps)*2)
+	for _, pass := range [][]gmath.Vec{ps, rev} {
+		start := len(hull)
+		for _, p := range pass {
+			for len(hull) >= start+2 && turn(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		// 終点は次の半分の始点になるので外す
+		hull = hull[:len(hull)-1]
+	}
+	return hull
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// 点集合の凸包から凸型多角形を作る
+// maxVerticesが3以上なら、その数まで頂点を減らす。0以下なら減らさない
+func NewConvexHull(points []gmath.Vec, maxVertices int) (*Polygon, error) {
+	vs := ConvexHull(points)
+	if maxVertices > 0 {
+		vs = SimplifyConvex(vs, maxVertices)
+	}
+
+	if err := ValidatePolygon(vs); err != nil {
+		return nil, err
+	}
+	return &Polygon{Vertices: vs}, nil
+}
+
+// 凸型多角形の頂点をn個まで減らす
+// 取り除いたときに失われる面積が一番小さい頂点から順に取り除くので、結果は元の形の内側に収まる
+func SimplifyConvex(vs []gmath.Vec, n int) []gmath.Vec {
+	n = max(n, 3)
+	vs = slices.Clone(vs)
+	for len(vs) > n {
+		idx := 0
+		best := math.Inf(1)
+		for i := range vs {
+			a := vs[(i+len(vs)-1)%len(vs)]
+			c := vs[(i+1)%len(vs)]
+			if s := math.Abs(turn(a, vs[i], c)); s < best {
+				best = s
+				idx = i
+			}
+		}
+		vs = slices.Delete(vs, idx, idx+1)
+	}
+	return vs
+}
@@ -0,0 +1,179 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 面積と質量の情報
+type MassData struct {
+	Area     float64   // 面積
+	Mass     float64   // 質量(面積×密度)
+	Centroid gmath.Vec // 重心(グローバル座標)
+	Inertia  float64   // 重心まわりの慣性モーメント
+}
+
+func (p *Polygon) Mass(density float64) MassData {
+	r := p.worldVertices()
+	if len(r) < 4 {
+		return MassData{}
+	}
+	return polygonMass(r[:len(r)-1], density)
+}
+
+func (c *Circle) Mass(density float64) MassData {
+	rx, ry := c.Radii()
+	area := math.Pi * rx * ry
+	m := area * density
+	return MassData{
+		Area:     area,
+		Mass:     m,
+		Centroid: c.Pos,
+		Inertia:  m * (rx*rx + ry*ry) / 4,
+	}
+}
+
+func (e *Ellipse) Mass(density float64) MassData {
+	area := math.Pi * e.RadiusX * e.RadiusY
+	m := area * density
+	return MassData{
+		Area:     area,
+		Mass:     m,
+		Centroid: e.Pos,
+		Inertia:  m * (e.RadiusX*e.RadiusX + e.RadiusY*e.RadiusY) / 4,
+	}
+}
+
+// 複合形状は演算子で組み合わせた領域の値になる
+// Orで子要素同士が重なっている場合も、重なった部分を二重に数えない
+func (c *Composit) Mass(density float64) MassData {
+	if c.Operator != CompositOr {
+		return piecesMass(disjointPieces(Pieces(c)), density)
+	}
+
+	result := make([]MassData, 0, len(c.Collisions))
+	for _, d := range c.Collisions {
+		result = append(result, MassOf(d, density))
+	}
+	if !childrenOverlap(c) {
+		return combineMass(result)
+	}
+
+	// 子要素ごとの値は重なっていない場合と同じ求め方のままにして、先の子要素と重なる部分を
+	// 多角形で近似して引く。重なりが無くなると引く値も0になるので、境目で値が飛ばない
+	var covered [][]gmath.Vec // 先の子要素の断片(重ならない)
+	for _, d := range c.Collisions {
+		p := disjointPieces(Pieces(d))
+		if overlap := intersectPieces(p, covered); len(overlap) > 0 {
+			result = append(result, negateMass(piecesMass(overlap, density)))
+		}
+		covered = append(covered, subtractPieces(p, covered)...)
+	}
+	return combineMass(result)
+}
+
+// 形状の面積と質量
+// Massを実装していない形状は多角形で近似して求める
+func MassOf(c Tester, density float64) MassData {
+	if m, ok := c.(interface{ Mass(float64) MassData }); ok {
+		return m.Mass(density)
+	}
+	return piecesMass(disjointPieces(Pieces(c)), density)
+}
+
+// 凸型多角形(右回り)の面積と質量
+// 1個目の頂点を基準にした三角形ごとに求めて足し合わせる
+func polygonMass(vs []gmath.Vec, density float64) MassData {
+	o := vs[0]
+	area := 0.0
+	var center gmath.Vec
+	inertia := 0.0
+	for i := 1; i < len(vs)-1; i++ {
+		e1 := vs[i].Sub(o)
+		e2 := vs[i+1].Sub(o)
+		d := e1.X*e2.Y - e1.Y*e2.X
+		a := d * 0.5
+		area += a
+		center = center.Add(e1.Add(e2).Mulf(a / 3))
+
+		ix := e1.X*e1.X + e2.X*e1.X + e2.X*e2.X
+		iy := e1.Y*e1.Y + e2.Y*e1.Y + e2.Y*e2.Y
+		inertia += d / 12 * (ix + iy)
+	}
+	if area <= 0 {
+		return MassData{}
+	}
+
+	// 基準点まわりの慣性モーメントを重心まわりに移す
+	center = center.Divf(area)
+	m := area * density
+	return MassData{
+		Area:     area,
+		Mass:     m,
+		Centroid: center.Add(o),
+		Inertia:  inertia*density - m*center.LenSquared(),
+	}
+}
+
+// 重ならない断片の集合の面積と質量
+func piecesMass(pieces [][]gmath.Vec, density float64) MassData {
+	result := make([]MassData, 0, len(pieces))
+	for _, p := range pieces {
+		result = append(result, polygonMass(p, density))
+	}
+	return combineMass(result)
+}
+
+// 取り除く部分として足し合わせるための負の値
+func negateMass(m MassData) MassData {
+	return MassData{
+		Area:     -m.Area,
+		Mass:     -m.Mass,
+		Centroid: m.Centroid,
+		Inertia:  -m.Inertia,
+	}
+}
+
+// 重ならない形状の面積と質量を合計する
+// 取り除く部分は負の値で渡す
+func combineMass(ms []MassData) MassData {
+	var result MassData
+	var center gmath.Vec
+	for _, m := range ms {
+		result.Area += m.Area
+		result.Mass += m.Mass
+		center = center.Add(m.Centroid.Mulf(m.Area))
+	}
+	if result.Area <= 0 {
+		return MassData{}
+	}
+	result.Centroid = center.Divf(result.Area)
+
+	// 平行軸の定理で全体の重心まわりに移す
+	for _, m := range ms {
+		result.Inertia += m.Inertia + m.Mass*m.Centroid.DistanceSquaredTo(result.Centroid)
+	}
+	return result
+}
+
+// 断片同士が重ならないように、先にある断片と重なる部分を取り除く
+func disjointPieces(pieces [][]gmath.Vec) [][]gmath.Vec {
+	var result [][]gmath.Vec
+	for _, p := range pieces {
+		result = append(result, subtractPieces([][]gmath.Vec{p}, result)...)
+	}
+	return result
+}
+
+// 子要素同士が重なっているか
+func childrenOverlap(co *Composit) bool {
+	for i, a := range co.Collisions {
+		for _, b := range co.Collisions[i+1:] {
+			if a.Test(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package collision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 重なった多角形は重なった部分を二重に数えない
+func TestCompositMassOverlap(t *testing.T) {
+	c := &Composit{
+		Operator: CompositOr,
+		Collisions: []Tester{
+			&Polygon{Vertices: []gmath.Vec{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}},
+			&Polygon{Vertices: []gmath.Vec{{X: 5, Y: 5}, {X: 15, Y: 5}, {X: 15, Y: 15}, {X: 5, Y: 15}}},
+		},
+	}
+	m := c.Mass(2)
+	if math.Abs(m.Area-175) > 1e-9 || math.Abs(m.Mass-350) > 1e-9 {
+		t.Errorf("area = %v, mass = %v, want 175, 350", m.Area, m.Mass)
+	}
+	if want := (gmath.Vec{X: 7.5, Y: 7.5}); m.Centroid.DistanceTo(want) > 1e-9 {
+		t.Errorf("centroid = %v, want %v", m.Centroid, want)
+	}
+}
+
+// 円が重なり始める前後で値が飛ばない
+func TestCompositMassContinuous(t *testing.T) {
+	a := &Circle{Radius: 10}
+	b := &Circle{Radius: 10}
+	c := &Composit{Operator: CompositOr, Collisions: []Tester{a, b}}
+
+	exact := 2 * math.Pi * 100
+	var prev MassData
+	for i := 0; i <= 200; i++ {
+		// 中心間の距離を21から19まで縮める
+		b.Pos.X = 21 - float64(i)*0.01
+		m := c.Mass(1)
+		if i == 0 {
+			if math.Abs(m.Area-exact) > 1e-9 {
+				t.Fatalf("separated area = %v, want %v", m.Area, exact)
+			}
+		} else {
+			if d := math.Abs(m.Area - prev.Area); d > 0.5 {
+				t.Fatalf("area jumps by %v at distance %v", d, b.Pos.X)
+			}
+			if d := m.Centroid.DistanceTo(prev.Centroid); d > 0.01 {
+				t.Fatalf("centroid jumps by %v at distance %v", d, b.Pos.X)
+			}
+			if m.Area > prev.Area+1e-9 {
+				t.Fatalf("area grows from %v to %v at distance %v", prev.Area, m.Area, b.Pos.X)
+			}
+		}
+		prev = m
+	}
+}
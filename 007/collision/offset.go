@@ -0,0 +1,199 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// オフセットしたときの角のつなぎ方
+type JoinType int
+
+const (
+	JoinMiter JoinType = 0 // エッジを延長して尖らせる
+	JoinRound JoinType = 1 // 円弧で丸める
+)
+
+// マイター結合で角がこれ以上(オフセット距離の倍数)尖る場合は面取りする
+const miterLimit = 4.0
+
+// 凸型多角形(右回り)の頂点集合を距離dだけ外側に広げる
+// dが負の場合は内側に縮め、消えてしまう場合はnilを返す。縮める場合の角は常に尖る
+func OffsetPolygon(vs []gmath.Vec, d float64, join JoinType) []gmath.Vec {
+	if len(vs) < 3 {
+		return nil
+	}
+	if d < 0 {
+		return shrinkPolygon(vs, -d)
+	}
+
+	result := make([]gmath.Vec, 0, len(vs)*2)
+	for i := range vs {
+		prev := vs[(i+len(vs)-1)%len(vs)]
+		v := vs[i]
+		next := vs[(i+1)%len(vs)]
+
+		// 前後のエッジの外向き法線
+		e1 := v.Sub(prev)
+		e2 := next.Sub(v)
+		n1 := gmath.Vec{X: e1.Y, Y: -e1.X}.Normalized()
+		n2 := gmath.Vec{X: e2.Y, Y: -e2.X}.Normalized()
+
+		switch join {
+		case JoinRound:
+			// n1からn2まで円弧で回す
+			a1 := math.Atan2(n1.Y, n1.X)
+			a2 := math.Atan2(n2.Y, n2.X)
+			sweep := math.Mod(a2-a1+2*math.Pi, 2*math.Pi)
+			steps := int(math.Ceil(sweep / (2 * math.Pi / circleSegments)))
+			for k := 0; k <= steps; k++ {
+				a := a1
+				if steps > 0 {
+					a += sweep * float64(k) / float64(steps)
+				}
+				result = append(result, v.Add(gmath.Vec{X: math.Cos(a), Y: math.Sin(a)}.Mulf(d)))
+			}
+		default:
+			// 二等分線の方向に、エッジからの距離がdになるところまで延ばす
+			m := n1.Add(n2)
+			c := m.Dot(n1)
+			if c <= 0 || m.Len()/c > miterLimit {
+				result = append(result, v.Add(n1.Mulf(d)), v.Add(n2.Mulf(d)))
+				continue
+			}
+			result = append(result, v.Add(m.Mulf(d/c)))
+		}
+	}
+	return cleanOutline(result)
+}
+
+// 各エッジを距離dだけ内側に移した半平面で切り取る
+func shrinkPolygon(vs []gmath.Vec, d float64) []gmath.Vec {
+	r := vs
+	for i := range vs {
+		a := vs[i]
+		b := vs[(i+1)%len(vs)]
+		e := b.Sub(a)
+		n := gmath.Vec{X: -e.Y, Y: e.X}.Normalized().Mulf(d) // 内向き法線
+		r = clipHalfPlane(r, a.Add(n), b.Add(n), true)
+		if r == nil {
+			return nil
+		}
+	}
+	return r
+}
+
+// 形状を距離dだけ広げた(負なら縮めた)形状を作る
+// 位置や回転角度はそのまま引き継ぐ。距離は拡大率をかける前の大きさで、消えてしまう場合はnilを返す
+// 複合形状は子要素ごとに広げる。AndとNotを広げる場合は近似になる
+// 子要素が複数あるOrを縮める場合は、子要素の境目が開かないように全体の輪郭から縮める(shrinkUnion)
+func Offset(c Tester, d float64, join JoinType) Tester {
+	switch v := c.(type) {
+	case *Polygon:
+		vs := OffsetPolygon(v.Vertices, d, join)
+		if vs == nil {
+			return nil
+		}
+		return &Polygon{Pos: v.Pos, Rad: v.Rad, Vertices: vs, Origin: v.Origin, Scale: v.Scale}
+	case *Circle:
+		if v.Radius+d <= 0 {
+			return nil
+		}
+		r := *v
+		r.Radius += d
+		return &r
+	case *Capsule:
+		if v.Radius+d <= 0 {
+			return nil
+		}
+		r := *v
+		r.Radius += d
+		return &r
+	case *Segment:
+		return Offset(v.polygon(), d, join)
+	case *AABB:
+		if join == JoinRound && d > 0 {
+			return Offset(v.polygon(), d, join)
+		}
+		if v.Width+d*2 <= 0 || v.Height+d*2 <= 0 {
+			return nil
+		}
+		return &AABB{Pos: v.Pos, Width: v.Width + d*2, Height: v.Height + d*2}
+	case *Composit:
+		return offsetComposit(v, d, join)
+	}
+
+	// それ以外の形状は断片ごとに広げる
+	result := &Composit{Operator: CompositOr}
+	for _, p := range Pieces(c) {
+		if vs := OffsetPolygon(p, d, join); vs != nil {
+			result.Collisions = append(result.Collisions, &Polygon{Vertices: vs})
+		}
+	}
+	if len(result.Collisions) == 0 {
+		return nil
+	}
+	return result
+}
+
+// 複合形状のオフセット
+// Notは取り除く側を逆に縮める。Xorは断片のOrとして広げる
+func offsetComposit(co *Composit, d float64, join JoinType) Tester {
+	if d < 0 && co.Operator == CompositOr && len(co.Collisions) > 1 {
+		return shrinkUnion(co, -d)
+	}
+	if co.Operator == CompositXor {
+		return Offset(&Composit{Collisions: pieceTesters(co), Operator: CompositOr, Filter: co.Filter}, d, join)
+	}
+
+	result := &Composit{Operator: co.Operator, Filter: co.Filter}
+	for i, c := range co.Collisions {
+		cd := d
+		if co.Operator == CompositNot && i > 0 {
+			cd = -d
+		}
+
+		o := Offset(c, cd, join)
+		if o == nil {
+			// 残す側が消えれば全体が消える
+			if co.Operator == CompositAnd || i == 0 {
+				return nil
+			}
+			continue
+		}
+		result.Collisions = append(result.Collisions, o)
+		if i < len(co.Locals) {
+			result.Locals = append(result.Locals, co.Locals[i])
+		}
+	}
+	if len(result.Collisions) == 0 {
+		return nil
+	}
+	return result
+}
+
+// 複合形状全体の輪郭から距離dだけ縮めた形状を作る
+// 子要素ごとに縮めると子要素同士の境目が2d幅で開いてしまうので、断片の和の輪郭を求め、
+// 輪郭の各エッジを半径dのカプセルにして取り除く。へこんだ角は丸くなる
+// 結果はグローバル座標の形状になるので、SetTransformで置き直すことはできない
+func shrinkUnion(co *Composit, d float64) Tester {
+	var region [][]gmath.Vec
+	for _, p := range Pieces(co) {
+		region = ClipRegions(region, [][]gmath.Vec{p}, ClipUnion)
+	}
+
+	result := &Composit{
+		Operator:   CompositNot,
+		Collisions: []Tester{&Composit{Operator: CompositOr, Collisions: pieceTesters(co)}},
+		Filter:     co.Filter,
+	}
+	for _, o := range region {
+		for i := range o {
+			result.Collisions = append(result.Collisions, &Capsule{A: o[i], B: o[(i+1)%len(o)], Radius: d})
+		}
+	}
+	if len(Pieces(result)) == 0 {
+		return nil
+	}
+	return result
+}
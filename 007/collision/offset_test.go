@@ -0,0 +1,77 @@
+package collision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 点から輪郭までの距離
+func outlineDistance(p gmath.Vec, vs []gmath.Vec) float64 {
+	d := math.Inf(1)
+	for i := range vs {
+		d = min(d, p.DistanceTo(closestPointOnSegment(p, vs[i], vs[(i+1)%len(vs)])))
+	}
+	return d
+}
+
+// 分解したL字を縮めても、断片の境目は開かずに外形全体が縮む
+func TestOffsetShrinkConcave(t *testing.T) {
+	outline := []gmath.Vec{{X: 0, Y: 0}, {X: 60, Y: 0}, {X: 60, Y: 20}, {X: 20, Y: 20}, {X: 20, Y: 60}, {X: 0, Y: 60}}
+	l, err := NewConcave(outline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Collisions) < 2 {
+		t.Fatalf("L shape decomposed into %d pieces, want at least 2", len(l.Collisions))
+	}
+
+	const d = 5.0
+	shrunk := Offset(l, -d, JoinMiter)
+	if shrunk == nil {
+		t.Fatal("Offset removed the whole shape")
+	}
+
+	// 断片の境目(外形に乗っていないエッジ)の中点は、新しい外形の十分内側にあれば中に残る
+	seams := 0
+	for _, p := range Pieces(l) {
+		for i := range p {
+			m := p[i].Add(p[(i+1)%len(p)]).Mulf(0.5)
+			if outlineDistance(m, outline) < 1e-9 {
+				continue
+			}
+			seams++
+			// 境目の両端は外形上にあるので、中点付近で外形から離れた点を調べる
+			if outlineDistance(m, outline) > d+1 && !TestPoint(m.X, m.Y, shrunk) {
+				t.Errorf("seam midpoint %v is not inside the shrunk shape", m)
+			}
+		}
+	}
+	if seams == 0 {
+		t.Fatal("no seam between the pieces")
+	}
+
+	// 外形から離れた点は内側、近い点は外側になる
+	for y := 0.5; y < 60; y++ {
+		for x := 0.5; x < 60; x++ {
+			p := gmath.Vec{X: x, Y: y}
+			if windingNumber(p, [][]gmath.Vec{outline}) == 0 {
+				continue
+			}
+			dist := outlineDistance(p, outline)
+			got := TestPoint(x, y, shrunk)
+			switch {
+			case dist > d+0.1 && !got:
+				t.Errorf("(%v, %v) is %v inside the outline but not in the shrunk shape", x, y, dist)
+			case dist < d-0.1 && got:
+				t.Errorf("(%v, %v) is %v inside the outline but still in the shrunk shape", x, y, dist)
+			}
+		}
+	}
+
+	// 全体より大きく縮めると消える
+	if s := Offset(l, -15, JoinMiter); s != nil {
+		t.Errorf("Offset(-15) = %v, want nil", s)
+	}
+}
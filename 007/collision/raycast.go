@@ -0,0 +1,303 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// レイとの衝突判定ができる衝突判定範囲のインターフェース
+type Raycaster interface {
+	Raycast(origin, dir gmath.Vec, maxDist float64) (RayHit, bool)
+}
+
+// レイの衝突情報
+type RayHit struct {
+	Distance float64   // 始点から衝突点までの距離
+	Point    gmath.Vec // 衝突点
+	Normal   gmath.Vec // 衝突した面の法線
+}
+
+// レイが形状の内側にある区間
+// 始点からの距離で表す
+type rayInterval struct {
+	in     float64   // 入る距離
+	out    float64   // 出る距離
+	normal gmath.Vec // 入る場所の法線
+}
+
+func (p *Polygon) Raycast(origin, dir gmath.Vec, maxDist float64) (RayHit, bool) {
+	return RaycastPolygon(origin, dir, maxDist, p)
+}
+
+func (c *Circle) Raycast(origin, dir gmath.Vec, maxDist float64) (RayHit, bool) {
+	if s := scaledShape(c); s != Tester(c) {
+		return raycastTester(origin, dir, maxDist, s)
+	}
+	return RaycastCircle(origin, dir, maxDist, c)
+}
+
+func (c *Composit) Raycast(origin, dir gmath.Vec, maxDist float64) (RayHit, bool) {
+	return RaycastComposit(origin, dir, maxDist, c)
+}
+
+func (c *Capsule) Raycast(origin, dir gmath.Vec, maxDist float64) (RayHit, bool) {
+	return raycastConvex(origin, dir, maxDist, c)
+}
+
+func (s *Segment) Raycast(origin, dir gmath.Vec, maxDist float64) (RayHit, bool) {
+	return raycastConvex(origin, dir, maxDist, s)
+}
+
+func (e *Ellipse) Raycast(origin, dir gmath.Vec, maxDist float64) (RayHit, bool) {
+	return raycastConvex(origin, dir, maxDist, e)
+}
+
+func (b *AABB) Raycast(origin, dir gmath.Vec, maxDist float64) (RayHit, bool) {
+	return raycastConvex(origin, dir, maxDist, b)
+}
+
+// Raycasterを実装していない形状は凸形状として判定する
+func raycastTester(origin, dir gmath.Vec, maxDist float64, c Tester) (RayHit, bool) {
+	if r, ok := c.(Raycaster); ok {
+		return r.Raycast(origin, dir, maxDist)
+	}
+	return raycastConvex(origin, dir, maxDist, c)
+}
+
+// レイと凸形状の判定
+func raycastConvex(origin, dir gmath.Vec, maxDist float64, c Tester) (RayHit, bool) {
+	d := dir.Normalized()
+	iv, ok := rayShape(origin, d, c)
+	if !ok {
+		return RayHit{}, false
+	}
+	return iv.hit(origin, d, maxDist)
+}
+
+// レイと凸型多角形の判定
+// dirは正規化しなくてもよい。始点が内側にある場合は距離0で当たったことにする
+func RaycastPolygon(origin, dir gmath.Vec, maxDist float64, p *Polygon) (RayHit, bool) {
+	d := dir.Normalized()
+	iv, ok := rayPolygon(origin, d, p)
+	if !ok {
+		return RayHit{}, false
+	}
+	return iv.hit(origin, d, maxDist)
+}
+
+// レイと円の判定
+func RaycastCircle(origin, dir gmath.Vec, maxDist float64, c *Circle) (RayHit, bool) {
+	d := dir.Normalized()
+	iv, ok := rayCircle(origin, d, c)
+	if !ok {
+		return RayHit{}, false
+	}
+	return iv.hit(origin, d, maxDist)
+}
+
+// レイと複合形状の判定
+// Orの場合は一番近い子要素、Andの場合は全ての子要素の内側になる最初の場所で当たる
+// Not/Xorや入れ子のAndの場合は領域の断片のうち一番近いものに当たる
+func RaycastComposit(origin, dir gmath.Vec, maxDist float64, co *Composit) (RayHit, bool) {
+	d := dir.Normalized()
+
+	children := co.Collisions
+	switch {
+	case co.isRegion():
+		children = pieceTesters(co)
+	case co.Operator == CompositAnd && !hasComposit(co):
+		iv, ok := rayComposit(origin, d, co)
+		if !ok {
+			return RayHit{}, false
+		}
+		return iv.hit(origin, d, maxDist)
+	case co.Operator == CompositAnd:
+		children = pieceTesters(co)
+	}
+
+	var result RayHit
+	found := false
+	for _, c := range children {
+		if h, ok := raycastTester(origin, d, maxDist, c); ok && (!found || h.Distance < result.Distance) {
+			result = h
+			found = true
+		}
+	}
+
+	return result, found
+}
+
+// 区間から衝突情報を作る
+func (iv rayInterval) hit(origin, d gmath.Vec, maxDist float64) (RayHit, bool) {
+	if iv.out < 0 || iv.in > maxDist || iv.in > iv.out {
+		return RayHit{}, false
+	}
+
+	// 始点が内側
+	if iv.in < 0 {
+		return RayHit{Distance: 0, Point: origin, Normal: d.Neg()}, true
+	}
+
+	return RayHit{Distance: iv.in, Point: origin.Add(d.Mulf(iv.in)), Normal: iv.normal}, true
+}
+
+// 凸型多角形の各エッジでレイを切り取る(Cyrus-Beck)
+func rayPolygon(origin, d gmath.Vec, p *Polygon) (rayInterval, bool) {
+	// 頂点が足りない場合は面積が無いので当たらない
+	if len(p.Vertices) < 3 {
+		return rayInterval{}, false
+	}
+	r := p.worldVertices()
+	iv := rayInterval{in: math.Inf(-1), out: math.Inf(1)}
+
+	for i := 0; i < len(r)-1; i++ {
+		e := r[i+1].Sub(r[i])
+		n := gmath.Vec{X: e.Y, Y: -e.X}.Normalized() // エッジの外向き法線
+
+		num := n.Dot(r[i].Sub(origin))
+		denom := n.Dot(d)
+		if denom == 0 {
+			// エッジと平行で外側にある
+			if num < 0 {
+				return rayInterval{}, false
+			}
+			continue
+		}
+
+		t := num / denom
+		if denom < 0 {
+			// 外から中に入る
+			if t > iv.in {
+				iv.in = t
+				iv.normal = n
+			}
+		} else if t < iv.out {
+			// 中から外に出る
+			iv.out = t
+		}
+	}
+
+	return iv, iv.in <= iv.out
+}
+
+// レイと円の交点を求める
+func rayCircle(origin, d gmath.Vec, c *Circle) (rayInterval, bool) {
+	m := origin.Sub(c.Pos)
+	b := m.Dot(d)
+	disc := b*b - m.LenSquared() + c.Radius*c.Radius
+	if disc < 0 {
+		return rayInterval{}, false
+	}
+
+	s := math.Sqrt(disc)
+	in := -b - s
+	return rayInterval{
+		in:     in,
+		out:    -b + s,
+		normal: origin.Add(d.Mulf(in)).Sub(c.Pos).Normalized(),
+	}, true
+}
+
+// 凸形状の区間を求める
+func rayShape(origin, d gmath.Vec, c Tester) (rayInterval, bool) {
+	switch v := scaledShape(c).(type) {
+	case *Polygon:
+		return rayPolygon(origin, d, v)
+	case *Circle:
+		return rayCircle(origin, d, v)
+	case *Capsule:
+		return rayCapsule(origin, d, v)
+	case *Segment:
+		return rayPolygon(origin, d, v.polygon())
+	case *Ellipse:
+		return rayEllipse(origin, d, v)
+	case *AABB:
+		return rayPolygon(origin, d, v.polygon())
+	case Convex:
+		return rayPolygon(origin, d, &Polygon{Vertices: supportOutline(v)})
+	}
+	return rayInterval{}, false
+}
+
+// カプセルは胴体の矩形と両端の円の和になる
+// 凸形状なので区間は1つにまとまる
+func rayCapsule(origin, d gmath.Vec, c *Capsule) (rayInterval, bool) {
+	a, b := c.endpoints()
+	parts := []Tester{
+		&Circle{Pos: a, Radius: c.Radius},
+		&Circle{Pos: b, Radius: c.Radius},
+		&Segment{A: a, B: b, Thickness: c.Radius * 2},
+	}
+
+	iv := rayInterval{in: math.Inf(1), out: math.Inf(-1)}
+	found := false
+	for _, p := range parts {
+		piv, ok := rayShape(origin, d, p)
+		if !ok || piv.in > piv.out {
+			continue
+		}
+		if piv.in < iv.in {
+			iv.in = piv.in
+			iv.normal = piv.normal
+		}
+		iv.out = max(iv.out, piv.out)
+		found = true
+	}
+	return iv, found
+}
+
+// 楕円を単位円に変換して交点を求める
+// 方向を正規化しないので、変換後も距離の比率は変わらない
+func rayEllipse(origin, d gmath.Vec, e *Ellipse) (rayInterval, bool) {
+	scale := gmath.Vec{X: 1 / e.RadiusX, Y: 1 / e.RadiusY}
+	o := origin.Sub(e.Pos).Rotated(-e.Rad).Mul(scale)
+	ld := d.Rotated(-e.Rad).Mul(scale)
+
+	qa := ld.LenSquared()
+	qb := o.Dot(ld)
+	disc := qb*qb - qa*(o.LenSquared()-1)
+	if disc < 0 {
+		return rayInterval{}, false
+	}
+
+	s := math.Sqrt(disc)
+	in := (-qb - s) / qa
+
+	// 法線は変換後の座標をもう一度スケールしてから戻す
+	p := o.Add(ld.Mulf(in)).Mul(scale)
+	return rayInterval{
+		in:     in,
+		out:    (-qb + s) / qa,
+		normal: p.Rotated(e.Rad).Normalized(),
+	}, true
+}
+
+// 子要素に複合形状を含んでいるか
+func hasComposit(co *Composit) bool {
+	for _, c := range co.Collisions {
+		if _, ok := c.(*Composit); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// And条件の複合形状は子要素の区間の共通部分になる
+func rayComposit(origin, d gmath.Vec, co *Composit) (rayInterval, bool) {
+	iv := rayInterval{in: math.Inf(-1), out: math.Inf(1)}
+	for _, c := range co.Collisions {
+		civ, ok := rayShape(origin, d, c)
+		if !ok {
+			return rayInterval{}, false
+		}
+
+		if civ.in > iv.in {
+			iv.in = civ.in
+			iv.normal = civ.normal
+		}
+		iv.out = min(iv.out, civ.out)
+	}
+
+	return iv, len(co.Collisions) > 0 && iv.in <= iv.out
+}
@@ -0,0 +1,131 @@
+package collision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+func checkHit(t *testing.T, name string, h RayHit, ok bool, dist float64, point, normal gmath.Vec) {
+	t.Helper()
+	if !ok {
+		t.Errorf("%s: no hit", name)
+		return
+	}
+	if math.Abs(h.Distance-dist) > 1e-9 || h.Point.DistanceTo(point) > 1e-9 || h.Normal.DistanceTo(normal) > 1e-9 {
+		t.Errorf("%s: hit = %+v, want distance %v at %v with normal %v", name, h, dist, point, normal)
+	}
+}
+
+func TestRaycastPolygon(t *testing.T) {
+	p := squarePolygon(100, 100, 20)
+
+	h, ok := RaycastPolygon(gmath.Vec{X: 50, Y: 110}, gmath.Vec{X: 2}, 100, p)
+	checkHit(t, "from the left", h, ok, 50, gmath.Vec{X: 100, Y: 110}, gmath.Vec{X: -1})
+
+	h, ok = RaycastPolygon(gmath.Vec{X: 110, Y: 200}, gmath.Vec{Y: -1}, 100, p)
+	checkHit(t, "from below", h, ok, 80, gmath.Vec{X: 110, Y: 120}, gmath.Vec{Y: 1})
+
+	// 始点が内側なら距離0で、法線はレイの逆向き
+	h, ok = RaycastPolygon(gmath.Vec{X: 110, Y: 110}, gmath.Vec{X: 1, Y: 1}, 100, p)
+	checkHit(t, "from inside", h, ok, 0, gmath.Vec{X: 110, Y: 110}, gmath.Vec{X: 1, Y: 1}.Normalized().Neg())
+
+	if h, ok := RaycastPolygon(gmath.Vec{X: 50, Y: 110}, gmath.Vec{X: 1}, 49, p); ok {
+		t.Errorf("ray shorter than the distance hit %+v", h)
+	}
+	if h, ok := RaycastPolygon(gmath.Vec{X: 50, Y: 110}, gmath.Vec{X: -1}, 100, p); ok {
+		t.Errorf("ray pointing away hit %+v", h)
+	}
+}
+
+func TestRaycastCircle(t *testing.T) {
+	c := &Circle{Pos: gmath.Vec{X: 100, Y: 100}, Radius: 10}
+
+	h, ok := RaycastCircle(gmath.Vec{X: 100, Y: 50}, gmath.Vec{Y: 3}, 100, c)
+	checkHit(t, "from above", h, ok, 40, gmath.Vec{X: 100, Y: 90}, gmath.Vec{Y: -1})
+
+	d := gmath.Vec{X: 1, Y: 1}.Normalized()
+	h, ok = RaycastCircle(gmath.Vec{X: 100, Y: 100}.Sub(d.Mulf(30)), d, 100, c)
+	checkHit(t, "diagonal", h, ok, 20, gmath.Vec{X: 100, Y: 100}.Sub(d.Mulf(10)), d.Neg())
+
+	h, ok = RaycastCircle(gmath.Vec{X: 105, Y: 100}, gmath.Vec{X: -1}, 100, c)
+	checkHit(t, "from inside", h, ok, 0, gmath.Vec{X: 105, Y: 100}, gmath.Vec{X: 1})
+
+	if h, ok := RaycastCircle(gmath.Vec{X: 111, Y: 50}, gmath.Vec{Y: 1}, 100, c); ok {
+		t.Errorf("ray passing beside the circle hit %+v", h)
+	}
+}
+
+// 取り除いた部分はレイが素通りする
+func TestRaycastNot(t *testing.T) {
+	// 真ん中を縦に取り除いて2本の棒にする
+	bars := &Composit{
+		Operator: CompositNot,
+		Collisions: []Tester{
+			squarePolygon(0, 0, 60),
+			&Polygon{Vertices: []gmath.Vec{{X: 20, Y: -10}, {X: 40, Y: -10}, {X: 40, Y: 70}, {X: 20, Y: 70}}},
+		},
+	}
+
+	h, ok := bars.Raycast(gmath.Vec{X: -10, Y: 30}, gmath.Vec{X: 1}, 100)
+	checkHit(t, "from the left", h, ok, 10, gmath.Vec{X: 0, Y: 30}, gmath.Vec{X: -1})
+
+	h, ok = bars.Raycast(gmath.Vec{X: 25, Y: 30}, gmath.Vec{X: 1}, 100)
+	checkHit(t, "from the removed part", h, ok, 15, gmath.Vec{X: 40, Y: 30}, gmath.Vec{X: -1})
+
+	h, ok = bars.Raycast(gmath.Vec{X: 25, Y: 30}, gmath.Vec{X: -1}, 100)
+	checkHit(t, "backwards from the removed part", h, ok, 5, gmath.Vec{X: 20, Y: 30}, gmath.Vec{X: 1})
+
+	if h, ok := bars.Raycast(gmath.Vec{X: 30, Y: -20}, gmath.Vec{Y: 1}, 100); ok {
+		t.Errorf("ray along the removed part hit %+v", h)
+	}
+
+	// リングの穴の中から撃つと、内側の円周で当たる
+	ring := &Composit{
+		Operator: CompositNot,
+		Collisions: []Tester{
+			&Circle{Pos: gmath.Vec{X: 50, Y: 50}, Radius: 30},
+			&Circle{Pos: gmath.Vec{X: 50, Y: 50}, Radius: 20},
+		},
+	}
+	h, ok = ring.Raycast(gmath.Vec{X: 50, Y: 50}, gmath.Vec{X: 1}, 100)
+	if !ok || math.Abs(h.Distance-20) > 1e-6 || h.Normal.X > -0.99 {
+		t.Errorf("ray from the hole = %+v, %v, want distance 20 facing back", h, ok)
+	}
+	h, ok = ring.Raycast(gmath.Vec{X: 0, Y: 50}, gmath.Vec{X: 1}, 100)
+	if !ok || math.Abs(h.Distance-20) > 1e-6 || h.Normal.X > -0.99 {
+		t.Errorf("ray from outside = %+v, %v, want distance 20 facing back", h, ok)
+	}
+}
+
+// Andは全ての子要素の内側に入った場所で当たる
+func TestRaycastAnd(t *testing.T) {
+	lens := &Composit{
+		Operator: CompositAnd,
+		Collisions: []Tester{
+			&Circle{Pos: gmath.Vec{X: 0, Y: 0}, Radius: 20},
+			&Circle{Pos: gmath.Vec{X: 30, Y: 0}, Radius: 20},
+		},
+	}
+
+	// 1つ目の円にはx=-20で入るが、レンズにはx=10で入る
+	h, ok := lens.Raycast(gmath.Vec{X: -50, Y: 0}, gmath.Vec{X: 1}, 100)
+	checkHit(t, "along the axis", h, ok, 60, gmath.Vec{X: 10, Y: 0}, gmath.Vec{X: -1})
+
+	// 両方の円の境界が交わる高さより内側で入る
+	y := math.Sqrt(20*20 - 15*15)
+	h, ok = lens.Raycast(gmath.Vec{X: 15, Y: -50}, gmath.Vec{Y: 1}, 100)
+	if !ok || math.Abs(h.Distance-(50-y)) > 1e-9 {
+		t.Errorf("vertical ray = %+v, %v, want distance %v", h, ok, 50-y)
+	}
+
+	// 両方の円を通っても、同時に内側にならなければ当たらない
+	if h, ok := lens.Raycast(gmath.Vec{X: -50, Y: 15}, gmath.Vec{X: 1}, 200); ok {
+		t.Errorf("ray through both circles but not the lens hit %+v", h)
+	}
+
+	// 始点がレンズの内側
+	h, ok = lens.Raycast(gmath.Vec{X: 15, Y: 0}, gmath.Vec{Y: 1}, 100)
+	checkHit(t, "from inside", h, ok, 0, gmath.Vec{X: 15, Y: 0}, gmath.Vec{Y: -1})
+}
@@ -0,0 +1,352 @@
+package collision
+
+import (
+	"math"
+	"slices"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 円を多角形に近似するときの頂点数
+const circleSegments = 32
+
+// これより面積が小さい断片は捨てる
+const pieceEpsilon = 1e-9
+
+// 形状を凸多角形の集合(グローバル座標、右回り)に分解する
+// 集合の和が元の形状になる。円は多角形で近似する
+func Pieces(c Tester) [][]gmath.Vec {
+	switch d := c.(type) {
+	case *Polygon:
+		if len(d.Vertices) < 3 {
+			return nil
+		}
+		r := d.worldVertices()
+		return [][]gmath.Vec{slices.Clone(r[:len(r)-1])}
+	case *Circle:
+		if s := scaledShape(d); s != Tester(d) {
+			return Pieces(s)
+		}
+		return [][]gmath.Vec{circleVertices(d.Pos, d.Radius, circleSegments)}
+	case *Capsule:
+		return [][]gmath.Vec{d.outline()}
+	case *Segment:
+		return Pieces(d.polygon())
+	case *Ellipse:
+		return [][]gmath.Vec{d.outline()}
+	case *AABB:
+		return Pieces(d.polygon())
+	case *Composit:
+		return compositPieces(d)
+	case Convex:
+		return [][]gmath.Vec{supportOutline(d)}
+	}
+
+	return nil
+}
+
+// 複合形状の演算子に従って断片を組み合わせる
+func compositPieces(co *Composit) [][]gmath.Vec {
+	var result [][]gmath.Vec
+	for i, d := range co.Collisions {
+		p := Pieces(d)
+		if i == 0 {
+			result = p
+			continue
+		}
+
+		switch co.Operator {
+		case CompositOr:
+			result = append(result, p...)
+		case CompositAnd:
+			result = intersectPieces(result, p)
+		case CompositNot:
+			result = subtractPieces(result, p)
+		case CompositXor:
+			result = append(subtractPieces(result, p), subtractPieces(p, result)...)
+		}
+	}
+
+	return result
+}
+
+// 中心と半径から円周上の頂点を右回りに作る
+func circleVertices(pos gmath.Vec, r float64, n int) []gmath.Vec {
+	vs := make([]gmath.Vec, 0, n)
+	for i := 0; i < n; i++ {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		vs = append(vs, gmath.Vec{X: pos.X + math.Cos(a)*r, Y: pos.Y + math.Sin(a)*r})
+	}
+	return vs
+}
+
+// 断片の集合同士の共通部分
+func intersectPieces(a, b [][]gmath.Vec) [][]gmath.Vec {
+	var result [][]gmath.Vec
+	for _, p := range a {
+		for _, q := range b {
+			if r := intersectConvex(p, q); r != nil {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
+
+// 断片の集合aからbを取り除く
+func subtractPieces(a, b [][]gmath.Vec) [][]gmath.Vec {
+	result := a
+	for _, q := range b {
+		next := make([][]gmath.Vec, 0, len(result))
+		for _, p := range result {
+			next = append(next, subtractConvex(p, q)...)
+		}
+		result = next
+	}
+	return result
+}
+
+// 凸多角形同士の共通部分(Sutherland-Hodgman)
+// 重なっていない場合はnilを返す
+func intersectConvex(p, q []gmath.Vec) []gmath.Vec {
+	r := p
+	for i := range q {
+		r = clipHalfPlane(r, q[i], q[(i+1)%len(q)], true)
+		if r == nil {
+			return nil
+		}
+	}
+	return r
+}
+
+// 凸多角形pから凸多角形qを取り除いた残りを、重ならない凸多角形に分けて返す
+// qの各エッジの外側を順番に切り出していく
+func subtractConvex(p, q []gmath.Vec) [][]gmath.Vec {
+	var result [][]gmath.Vec
+	rest := p
+	for i := range q {
+		a := q[i]
+		b := q[(i+1)%len(q)]
+		if out := clipHalfPlane(rest, a, b, false); out != nil {
+			result = append(result, out)
+		}
+		rest = clipHalfPlane(rest, a, b, true)
+		if rest == nil {
+			break
+		}
+	}
+	return result
+}
+
+// 凸多角形をエッジa→bを通る直線で切り取る
+// insideがtrueならエッジの内側、falseなら外側を残す
+func clipHalfPlane(p []gmath.Vec, a, b gmath.Vec, inside bool) []gmath.Vec {
+	e := b.Sub(a)
+
+	// 外積が正なら外側(TestPointPolygonと同じ)
+	side := func(v gmath.Vec) float64 {
+		w := v.Sub(a)
+		s := w.X*e.Y - e.X*w.Y
+		if inside {
+			return -s
+		}
+		return s
+	}
+
+	r := make([]gmath.Vec, 0, len(p)+1)
+	for i := range p {
+		v1 := p[i]
+		v2 := p[(i+1)%len(p)]
+		s1 := side(v1)
+		s2 := side(v2)
+		if s1 >= 0 {
+			r = append(r, v1)
+		}
+		if s1*s2 < 0 {
+			r = append(r, v1.Add(v2.Sub(v1).Mulf(s1/(s1-s2))))
+		}
+	}
+
+	if len(r) < 3 || math.Abs(polygonArea(r)) < pieceEpsilon {
+		return nil
+	}
+	return r
+}
+
+// 多角形の符号付き面積(右回りで正)
+func polygonArea(vs []gmath.Vec) float64 {
+	a := 0.0
+	for i := range vs {
+		v1 := vs[i]
+		v2 := vs[(i+1)%len(vs)]
+		a += v1.X*v2.Y - v2.X*v1.Y
+	}
+	return a * 0.5
+}
+
+// 演算子で組み合わせた領域として判定する必要があるか
+// Orは子要素ごとに判定すればよい
+func (c *Composit) isRegion() bool {
+	return c.Operator == CompositNot || c.Operator == CompositXor
+}
+
+// 断片を凸多角形の衝突判定範囲にする
+func pieceTesters(c Tester) []Tester {
+	pieces := Pieces(c)
+	result := make([]Tester, 0, len(pieces))
+	for _, p := range pieces {
+		result = append(result, &Polygon{Vertices: p})
+	}
+	return result
+}
+
+// 複合形状を断片ごとの凸多角形として判定する
+func testRegion(o Tester, co *Composit) bool {
+	for _, p := range pieceTesters(co) {
+		if o.Test(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// 判定の誤差の許容範囲
+const regionTolerance = 1e-9
+
+// And条件の複合形状を共通部分の領域として判定する
+func testIntersection(a, b Tester) bool {
+	pa, ca, ok1 := convexParts(a)
+	pb, cb, ok2 := convexParts(b)
+	if ok1 && ok2 {
+		return intersectsConvexSets(append(pa, pb...), append(ca, cb...))
+	}
+
+	// 凸形状の共通部分にならない場合は断片で判定する
+	return intersectPieces(Pieces(a), Pieces(b)) != nil
+}
+
+// 形状を凸多角形と円の共通部分として表す
+// Or条件などで共通部分として表せない場合はfalseを返す
+func convexParts(c Tester) ([][]gmath.Vec, []*Circle, bool) {
+	switch d := c.(type) {
+	case *Polygon:
+		return Pieces(d), nil, len(d.Vertices) >= 3
+	case *Circle:
+		// 楕円になる場合は表せない
+		if s, ok := scaledShape(d).(*Circle); ok {
+			return nil, []*Circle{s}, true
+		}
+	case *Segment:
+		return convexParts(d.polygon())
+	case *AABB:
+		return convexParts(d.polygon())
+	case *Composit:
+		if d.Operator != CompositAnd && len(d.Collisions) != 1 {
+			return nil, nil, false
+		}
+
+		var polys [][]gmath.Vec
+		var circles []*Circle
+		for _, v := range d.Collisions {
+			p, c, ok := convexParts(v)
+			if !ok {
+				return nil, nil, false
+			}
+			polys = append(polys, p...)
+			circles = append(circles, c...)
+		}
+		return polys, circles, len(polys)+len(circles) > 0
+	}
+
+	return nil, nil, false
+}
+
+// 凸多角形と円の共通部分があるかを判定する
+// 共通部分があれば、その境界の角(多角形の頂点、円同士や円とエッジの交点)か、
+// 丸ごと含まれる円の中心のどれかが全ての形状の内側にある
+func intersectsConvexSets(polys [][]gmath.Vec, circles []*Circle) bool {
+	// 多角形同士は先に切り取っておく
+	var p []gmath.Vec
+	for i, q := range polys {
+		if i == 0 {
+			p = q
+			continue
+		}
+		if p = intersectConvex(p, q); p == nil {
+			return false
+		}
+	}
+	if len(circles) == 0 {
+		return p != nil
+	}
+
+	inside := func(v gmath.Vec) bool {
+		for _, c := range circles {
+			if v.DistanceTo(c.Pos) >= c.Radius+regionTolerance {
+				return false
+			}
+		}
+		for i := range p {
+			e := p[(i+1)%len(p)].Sub(p[i])
+			w := v.Sub(p[i])
+			if (w.X*e.Y-e.X*w.Y)/e.Len() > regionTolerance {
+				return false
+			}
+		}
+		return true
+	}
+
+	candidates := append([]gmath.Vec{}, p...)
+	for i, c := range circles {
+		candidates = append(candidates, c.Pos)
+		for _, c2 := range circles[i+1:] {
+			candidates = append(candidates, circleCircleIntersections(c, c2)...)
+		}
+		for j := range p {
+			candidates = append(candidates, circleSegmentIntersections(c, p[j], p[(j+1)%len(p)])...)
+		}
+	}
+
+	for _, v := range candidates {
+		if inside(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// 円周同士の交点
+func circleCircleIntersections(c1, c2 *Circle) []gmath.Vec {
+	d := c1.Pos.DistanceTo(c2.Pos)
+	if d == 0 || d > c1.Radius+c2.Radius || d < math.Abs(c1.Radius-c2.Radius) {
+		return nil
+	}
+
+	a := (d*d + c1.Radius*c1.Radius - c2.Radius*c2.Radius) / (2 * d)
+	h := math.Sqrt(math.Max(c1.Radius*c1.Radius-a*a, 0))
+	u := c2.Pos.Sub(c1.Pos).Divf(d)
+	m := c1.Pos.Add(u.Mulf(a))
+	n := gmath.Vec{X: -u.Y, Y: u.X}.Mulf(h)
+	return []gmath.Vec{m.Add(n), m.Sub(n)}
+}
+
+// 円周と線分の交点
+func circleSegmentIntersections(c *Circle, a, b gmath.Vec) []gmath.Vec {
+	d := b.Sub(a)
+	m := a.Sub(c.Pos)
+	qa := d.LenSquared()
+	qb := m.Dot(d)
+	disc := qb*qb - qa*(m.LenSquared()-c.Radius*c.Radius)
+	if qa == 0 || disc < 0 {
+		return nil
+	}
+
+	s := math.Sqrt(disc)
+	result := make([]gmath.Vec, 0, 2)
+	for _, t := range []float64{(-qb - s) / qa, (-qb + s) / qa} {
+		if t >= 0 && t <= 1 {
+			result = append(result, a.Add(d.Mulf(t)))
+		}
+	}
+	return result
+}
@@ -0,0 +1,162 @@
+package collision
+
+import (
+	"reflect"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 形状の組み合わせ
+type pairKey struct {
+	a, b reflect.Type
+}
+
+var (
+	pairTests  = map[pairKey]func(a, b Tester) bool{}
+	pointTests = map[reflect.Type]func(x, y float64, c Tester) bool{}
+)
+
+func init() {
+	RegisterPairTest(TestPolygonPolygon)
+	RegisterPairTest(TestCirclePolygon)
+	RegisterPairTest(TestCircleCircle)
+	RegisterPairTest(TestAABBAABB)
+	RegisterPairTest(TestCircleCapsule)
+	RegisterPairTest(TestCapsuleCapsule)
+
+	RegisterPointTest(TestPointPolygon)
+	RegisterPointTest(TestPointCircle)
+	RegisterPointTest(TestPointCapsule)
+	RegisterPointTest(TestPointSegment)
+	RegisterPointTest(TestPointEllipse)
+	RegisterPointTest(TestPointAABB)
+}
+
+// 形状の組み合わせに専用の判定関数を登録する
+// 引数を入れ替えた組み合わせにも使われる。登録されていない組み合わせは、
+// 両方がConvexを実装していればGJKで判定する
+func RegisterPairTest[A, B Tester](f func(a A, b B) bool) {
+	ta := reflect.TypeFor[A]()
+	tb := reflect.TypeFor[B]()
+	pairTests[pairKey{ta, tb}] = func(a, b Tester) bool {
+		return f(a.(A), b.(B))
+	}
+}
+
+// 形状に点との判定関数を登録する
+// 登録されていない形状は、Convexを実装していればGJKで判定する
+func RegisterPointTest[T Tester](f func(x, y float64, c T) bool) {
+	pointTests[reflect.TypeFor[T]()] = func(x, y float64, c Tester) bool {
+		return f(x, y, c.(T))
+	}
+}
+
+// 形状同士の判定
+// 各形状のTestはこれを呼べばよい
+func TestShapes(a, b Tester) bool {
+	a = scaledShape(a)
+	b = scaledShape(b)
+
+	ta := reflect.TypeOf(a)
+	tb := reflect.TypeOf(b)
+	if f, found := pairTests[pairKey{ta, tb}]; found {
+		return f(a, b)
+	}
+	if f, found := pairTests[pairKey{tb, ta}]; found {
+		return f(b, a)
+	}
+
+	ca, ok1 := a.(*Composit)
+	cb, ok2 := b.(*Composit)
+	switch {
+	case ok1 && ok2:
+		return TestCompositComposit(ca, cb)
+	case ok2:
+		return testComposit(a, cb)
+	case ok1:
+		return testComposit(b, ca)
+	}
+
+	va, ok1 := a.(Convex)
+	vb, ok2 := b.(Convex)
+	if ok1 && ok2 {
+		return TestConvexConvex(va, vb)
+	}
+	return false
+}
+
+// 点と形状の判定
+func TestPoint(x, y float64, c Tester) bool {
+	c = scaledShape(c)
+	if f, found := pointTests[reflect.TypeOf(c)]; found {
+		return f(x, y, c)
+	}
+
+	switch v := c.(type) {
+	case *Composit:
+		return TestPointComposit(x, y, v)
+	case Convex:
+		return TestConvexConvex(&point{X: x, Y: y}, v)
+	}
+	return false
+}
+
+// 形状と複合形状の判定
+func testComposit(o Tester, co *Composit) bool {
+	if co.isRegion() {
+		return testRegion(o, co)
+	}
+	if co.Operator == CompositAnd {
+		return testIntersection(o, co)
+	}
+
+	// いずれかの子要素と重なっている
+	for _, d := range co.Collisions {
+		if o.Test(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// GJKで点を判定するための大きさの無い形状
+type point gmath.Vec
+
+func (p *point) Test(o Tester) bool {
+	return TestShapes(p, o)
+}
+
+func (p *point) Support(d gmath.Vec) gmath.Vec {
+	return gmath.Vec(*p)
+}
+
+// サポート関数から外周を多角形で近似する(右回り)
+func supportOutline(c Convex) []gmath.Vec {
+	vs := make([]gmath.Vec, 0, circleSegments)
+	for _, d := range circleVertices(gmath.Vec{}, 1, circleSegments) {
+		v := c.Support(d)
+		if len(vs) > 0 && v.DistanceSquaredTo(vs[len(vs)-1]) < pieceEpsilon {
+			continue
+		}
+		vs = append(vs, v)
+	}
+	if len(vs) > 1 && vs[0].DistanceSquaredTo(vs[len(vs)-1]) < pieceEpsilon {
+		vs = vs[:len(vs)-1]
+	}
+	return vs
+}
+
+// 形状のAABB
+// Bounderを実装していない凸形状はサポート関数から求める
+func boundsOf(c Tester) (gmath.Rect, bool) {
+	switch v := c.(type) {
+	case Bounder:
+		return v.Bounds(), true
+	case Convex:
+		return gmath.Rect{
+			Min: gmath.Vec{X: v.Support(gmath.Vec{X: -1}).X, Y: v.Support(gmath.Vec{Y: -1}).Y},
+			Max: gmath.Vec{X: v.Support(gmath.Vec{X: 1}).X, Y: v.Support(gmath.Vec{Y: 1}).Y},
+		}, true
+	}
+	return gmath.Rect{}, false
+}
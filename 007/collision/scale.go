@@ -0,0 +1,43 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 拡大率のゼロの成分を等倍にする
+// Transform.ScaleやBase.Scaleの実際の拡大率を求めるのに使う
+func ScaleOf(s gmath.Vec) gmath.Vec {
+	if s.X == 0 {
+		s.X = 1
+	}
+	if s.Y == 0 {
+		s.Y = 1
+	}
+	return s
+}
+
+// 拡大率を反映したX方向とY方向の半径
+func (c *Circle) Radii() (float64, float64) {
+	s := ScaleOf(c.Scale)
+	return c.Radius * math.Abs(s.X), c.Radius * math.Abs(s.Y)
+}
+
+// 拡大率を反映した形状
+// 等倍の円はそのまま、一様な拡大率なら半径を変えた円、そうでなければ楕円になる
+func scaledShape(c Tester) Tester {
+	d, ok := c.(*Circle)
+	if !ok {
+		return c
+	}
+
+	rx, ry := d.Radii()
+	switch {
+	case rx == d.Radius && ry == d.Radius:
+		return d
+	case rx == ry:
+		return &Circle{Pos: d.Pos, Radius: rx}
+	}
+	return &Ellipse{Pos: d.Pos, Rad: d.Rad, RadiusX: rx, RadiusY: ry}
+}
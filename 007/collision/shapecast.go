@@ -0,0 +1,68 @@
+package collision
+
+import (
+	"github.com/quasilyte/gmath"
+)
+
+// 形状を動かしたときの衝突情報
+type ShapeHit struct {
+	Index    int       // 当たった相手のtargetsでのインデックス
+	Fraction float64   // 接触するまでに移動した割合(0〜1)
+	Normal   gmath.Vec // 当たった面の法線(相手から形状に向かう単位ベクトル)
+	Point    gmath.Vec // 接触点
+}
+
+// 形状をfromの姿勢からdeltaだけ平行移動させて、最初に当たる相手を探す
+// targetsは配置済みの衝突判定範囲で、書き換えない。最初から重なっている場合は割合0で当たる
+// 法線は接触した位置での衝突情報から求め、求められない場合は移動方向の逆向きにする
+func ShapeCast(shape Tester, from Transform, delta gmath.Vec, targets []Tester) (ShapeHit, bool) {
+	cs := clone(shape)
+	to := from
+	to.Pos = from.Pos.Add(delta)
+
+	// 移動範囲全体のAABBで相手を絞り込む
+	place(cs, from)
+	swept, bounded := boundsOf(cs)
+	place(cs, to)
+	if b, ok := boundsOf(cs); ok && bounded {
+		swept = unionRect(swept, b)
+	}
+
+	result := ShapeHit{Index: -1, Fraction: 1}
+	for i, t := range targets {
+		if tb, ok := boundsOf(t); ok && bounded && !overlapRect(swept, tb) {
+			continue
+		}
+
+		test := func(f float64) bool {
+			place(cs, from.Lerp(to, f))
+			return cs.Test(t)
+		}
+		f, ok := sweep(test, delta.Len(), min(minExtent(cs), minExtent(t))*0.5)
+		if ok && (result.Index < 0 || f < result.Fraction) {
+			result.Index = i
+			result.Fraction = f
+		}
+	}
+	if result.Index < 0 {
+		return ShapeHit{Index: -1, Fraction: 1}, false
+	}
+
+	// 接触した位置で衝突情報を求める
+	result.Normal = delta.Normalized().Neg()
+	result.Point = from.Pos.Add(delta.Mulf(result.Fraction))
+	place(cs, from.Lerp(to, result.Fraction))
+	if c, ok := cs.(Collider); ok {
+		if ct, ok := c.Collide(targets[result.Index]); ok {
+			result.Normal = ct.Normal.Neg()
+			if len(ct.Points) > 0 {
+				result.Point = ct.Points[0]
+			}
+		}
+	}
+	// 凸形状なら相手側に一番出ている点を接触点にする
+	if v, ok := cs.(Convex); ok {
+		result.Point = v.Support(result.Normal.Neg())
+	}
+	return result, true
+}
@@ -0,0 +1,67 @@
+package collision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// x=leftから幅10、高さ100の壁
+func wall(left float64) *Polygon {
+	return &Polygon{Vertices: []gmath.Vec{{X: left, Y: -50}, {X: left + 10, Y: -50}, {X: left + 10, Y: 50}, {X: left, Y: 50}}}
+}
+
+func TestShapeCastWall(t *testing.T) {
+	c := &Circle{Radius: 10}
+	h, ok := ShapeCast(c, Transform{}, gmath.Vec{X: 100}, []Tester{wall(50)})
+	if !ok {
+		t.Fatal("circle did not hit the wall")
+	}
+
+	// 中心がx=40まで進んだところで壁の面x=50に触れる
+	if h.Index != 0 || math.Abs(h.Fraction-0.4) > 1e-6 {
+		t.Errorf("hit = %+v, want index 0 at fraction 0.4", h)
+	}
+	if h.Normal.DistanceTo(gmath.Vec{X: -1}) > 1e-6 {
+		t.Errorf("normal = %v, want (-1, 0)", h.Normal)
+	}
+	if h.Point.DistanceTo(gmath.Vec{X: 50}) > 1e-4 {
+		t.Errorf("point = %v, want (50, 0)", h.Point)
+	}
+
+	// 元の形状は動かさない
+	if c.Pos != (gmath.Vec{}) {
+		t.Errorf("shape moved to %v", c.Pos)
+	}
+}
+
+func TestShapeCastNearest(t *testing.T) {
+	box := &Polygon{Vertices: square(-5, -5, 10)}
+	targets := []Tester{wall(80), wall(30), wall(60)}
+	h, ok := ShapeCast(box, Transform{Pos: gmath.Vec{X: 0}}, gmath.Vec{X: 100}, targets)
+	if !ok || h.Index != 1 {
+		t.Fatalf("hit = %+v, %v, want the wall at index 1", h, ok)
+	}
+	// 右端x=5がx=30に触れるまで25進む
+	if math.Abs(h.Fraction-0.25) > 1e-6 {
+		t.Errorf("fraction = %v, want 0.25", h.Fraction)
+	}
+
+	// 届かない場合は当たらない
+	if h, ok := ShapeCast(box, Transform{}, gmath.Vec{X: 20}, targets); ok {
+		t.Errorf("short cast hit %+v", h)
+	}
+	// 移動方向に無い相手には当たらない
+	if h, ok := ShapeCast(box, Transform{}, gmath.Vec{X: -100}, targets); ok {
+		t.Errorf("cast away from the walls hit %+v", h)
+	}
+}
+
+// 最初から重なっている場合は割合0で当たる
+func TestShapeCastStartOverlapping(t *testing.T) {
+	h, ok := ShapeCast(&Circle{Radius: 10}, Transform{Pos: gmath.Vec{X: 45}}, gmath.Vec{X: 100}, []Tester{wall(80), wall(50)})
+	if !ok || h.Index != 1 || h.Fraction != 0 {
+		t.Errorf("hit = %+v, %v, want the wall at index 1 at fraction 0", h, ok)
+	}
+}
@@ -0,0 +1,240 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// カプセル(線分を半径分太らせた形)
+type Capsule struct {
+	Pos    gmath.Vec // 座標
+	Rad    gmath.Rad // 回転角度(ラジアン)
+	A      gmath.Vec // 線分の始点(Posからの相対座標)
+	B      gmath.Vec // 線分の終点(Posからの相対座標)
+	Radius float64   // 半径
+}
+
+func (c *Capsule) Test(o Tester) bool {
+	return TestShapes(c, o)
+}
+
+// 線分の両端のグローバル座標
+func (c *Capsule) endpoints() (gmath.Vec, gmath.Vec) {
+	return c.A.Rotated(c.Rad).Add(c.Pos), c.B.Rotated(c.Rad).Add(c.Pos)
+}
+
+func (c *Capsule) Support(d gmath.Vec) gmath.Vec {
+	a, b := c.endpoints()
+	return supportPoints([]gmath.Vec{a, b}, d).Add(d.Normalized().Mulf(c.Radius))
+}
+
+// 外周を多角形で近似する
+// 終点側の半円、始点側の半円の順に右回りで並べる
+func (c *Capsule) outline() []gmath.Vec {
+	a, b := c.endpoints()
+	t := float64(b.Sub(a).Angle())
+	n := circleSegments / 2
+	vs := make([]gmath.Vec, 0, (n+1)*2)
+	for _, e := range []struct {
+		center gmath.Vec
+		start  float64
+	}{{b, t - math.Pi*0.5}, {a, t + math.Pi*0.5}} {
+		for i := 0; i <= n; i++ {
+			r := e.start + math.Pi*float64(i)/float64(n)
+			vs = append(vs, gmath.Vec{X: e.center.X + math.Cos(r)*c.Radius, Y: e.center.Y + math.Sin(r)*c.Radius})
+		}
+	}
+	return vs
+}
+
+func (c *Capsule) Bounds() gmath.Rect {
+	a, b := c.endpoints()
+	r := gmath.Vec{X: c.Radius, Y: c.Radius}
+	return unionRect(
+		gmath.Rect{Min: a.Sub(r), Max: a.Add(r)},
+		gmath.Rect{Min: b.Sub(r), Max: b.Add(r)},
+	)
+}
+
+// 太さのある線分(端は平ら)
+type Segment struct {
+	Pos       gmath.Vec // 座標
+	Rad       gmath.Rad // 回転角度(ラジアン)
+	A         gmath.Vec // 始点(Posからの相対座標)
+	B         gmath.Vec // 終点(Posからの相対座標)
+	Thickness float64   // 太さ
+}
+
+func (s *Segment) Test(o Tester) bool {
+	return TestShapes(s, o)
+}
+
+// 同じ形の凸型多角形
+func (s *Segment) polygon() *Polygon {
+	d := s.B.Sub(s.A).Normalized()
+	n := gmath.Vec{X: -d.Y, Y: d.X}.Mulf(s.Thickness * 0.5)
+	return &Polygon{
+		Pos: s.Pos,
+		Rad: s.Rad,
+		Vertices: []gmath.Vec{
+			s.A.Sub(n),
+			s.B.Sub(n),
+			s.B.Add(n),
+			s.A.Add(n),
+		},
+	}
+}
+
+func (s *Segment) Support(d gmath.Vec) gmath.Vec {
+	return s.polygon().Support(d)
+}
+
+func (s *Segment) Bounds() gmath.Rect {
+	return s.polygon().Bounds()
+}
+
+// 楕円
+type Ellipse struct {
+	Pos     gmath.Vec // 中心座標
+	Rad     gmath.Rad // 回転角度(ラジアン)
+	RadiusX float64   // 回転前のX方向の半径
+	RadiusY float64   // 回転前のY方向の半径
+}
+
+func (e *Ellipse) Test(o Tester) bool {
+	return TestShapes(e, o)
+}
+
+func (e *Ellipse) Support(d gmath.Vec) gmath.Vec {
+	// 回転前の座標系で求めてから戻す
+	l := d.Rotated(-e.Rad)
+	v := gmath.Vec{X: e.RadiusX * e.RadiusX * l.X, Y: e.RadiusY * e.RadiusY * l.Y}
+	n := math.Sqrt(e.RadiusX*e.RadiusX*l.X*l.X + e.RadiusY*e.RadiusY*l.Y*l.Y)
+	if n == 0 {
+		return e.Pos
+	}
+	return v.Divf(n).Rotated(e.Rad).Add(e.Pos)
+}
+
+// 外周を多角形で近似する
+func (e *Ellipse) outline() []gmath.Vec {
+	vs := circleVertices(gmath.Vec{}, 1, circleSegments)
+	for i, v := range vs {
+		vs[i] = gmath.Vec{X: v.X * e.RadiusX, Y: v.Y * e.RadiusY}.Rotated(e.Rad).Add(e.Pos)
+	}
+	return vs
+}
+
+func (e *Ellipse) Bounds() gmath.Rect {
+	sin, cos := math.Sincos(float64(e.Rad))
+	w := math.Hypot(e.RadiusX*cos, e.RadiusY*sin)
+	h := math.Hypot(e.RadiusX*sin, e.RadiusY*cos)
+	r := gmath.Vec{X: w, Y: h}
+	return gmath.Rect{Min: e.Pos.Sub(r), Max: e.Pos.Add(r)}
+}
+
+// 軸に平行な矩形(回転しない)
+type AABB struct {
+	Pos    gmath.Vec // 中心座標
+	Width  float64   // 幅
+	Height float64   // 高さ
+}
+
+func (b *AABB) Test(o Tester) bool {
+	return TestShapes(b, o)
+}
+
+// 同じ形の凸型多角形
+func (b *AABB) polygon() *Polygon {
+	w := b.Width * 0.5
+	h := b.Height * 0.5
+	return &Polygon{
+		Pos: b.Pos,
+		Vertices: []gmath.Vec{
+			{X: -w, Y: -h},
+			{X: w, Y: -h},
+			{X: w, Y: h},
+			{X: -w, Y: h},
+		},
+	}
+}
+
+func (b *AABB) Support(d gmath.Vec) gmath.Vec {
+	v := b.Pos
+	if d.X >= 0 {
+		v.X += b.Width * 0.5
+	} else {
+		v.X -= b.Width * 0.5
+	}
+	if d.Y >= 0 {
+		v.Y += b.Height * 0.5
+	} else {
+		v.Y -= b.Height * 0.5
+	}
+	return v
+}
+
+func (b *AABB) Bounds() gmath.Rect {
+	h := gmath.Vec{X: b.Width * 0.5, Y: b.Height * 0.5}
+	return gmath.Rect{Min: b.Pos.Sub(h), Max: b.Pos.Add(h)}
+}
+
+// 点とカプセルの判定
+func TestPointCapsule(x, y float64, c *Capsule) bool {
+	a, b := c.endpoints()
+	p := gmath.Vec{X: x, Y: y}
+	return closestPointOnSegment(p, a, b).DistanceSquaredTo(p) < c.Radius*c.Radius
+}
+
+// 点と太さのある線分の判定
+func TestPointSegment(x, y float64, s *Segment) bool {
+	return TestPointPolygon(x, y, s.polygon())
+}
+
+// 点と楕円の判定
+func TestPointEllipse(x, y float64, e *Ellipse) bool {
+	l := gmath.Vec{X: x, Y: y}.Sub(e.Pos).Rotated(-e.Rad)
+	nx := l.X / e.RadiusX
+	ny := l.Y / e.RadiusY
+	return nx*nx+ny*ny < 1
+}
+
+// 点と軸に平行な矩形の判定
+func TestPointAABB(x, y float64, b *AABB) bool {
+	r := b.Bounds()
+	return r.Min.X <= x && x <= r.Max.X && r.Min.Y <= y && y <= r.Max.Y
+}
+
+// 軸に平行な矩形同士の判定
+func TestAABBAABB(b1 *AABB, b2 *AABB) bool {
+	return overlapRect(b1.Bounds(), b2.Bounds())
+}
+
+// 円とカプセルの判定
+func TestCircleCapsule(c *Circle, cp *Capsule) bool {
+	a, b := cp.endpoints()
+	r := c.Radius + cp.Radius
+	return closestPointOnSegment(c.Pos, a, b).DistanceSquaredTo(c.Pos) < r*r
+}
+
+// カプセル同士の判定
+func TestCapsuleCapsule(c1 *Capsule, c2 *Capsule) bool {
+	a1, b1 := c1.endpoints()
+	a2, b2 := c2.endpoints()
+	r := c1.Radius + c2.Radius
+	return segmentDistanceSquared(a1, b1, a2, b2) < r*r
+}
+
+// 線分同士の距離の2乗
+func segmentDistanceSquared(a1, b1, a2, b2 gmath.Vec) float64 {
+	if segmentsIntersect(a1, b1, a2, b2) {
+		return 0
+	}
+	return min(
+		closestPointOnSegment(a1, a2, b2).DistanceSquaredTo(a1),
+		closestPointOnSegment(b1, a2, b2).DistanceSquaredTo(b1),
+		closestPointOnSegment(a2, a1, b1).DistanceSquaredTo(a2),
+		closestPointOnSegment(b2, a1, b1).DistanceSquaredTo(b2),
+	)
+}
@@ -0,0 +1,172 @@
+package collision
+
+import (
+	"math"
+	"slices"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 一様グリッドの空間ハッシュ(ブロードフェーズ)
+// 登録したAABBが同じセルに入っているものだけを衝突候補のペアとして返す
+type SpatialHash[T comparable] struct {
+	CellSize float64             // セルの大きさ
+	cells    map[cellKey][]T     // セルごとの登録済みアイテム
+	items    map[T]*spatialEntry // アイテムごとの登録情報
+	order    []T                 // 登録順(ペアの列挙順を安定させるため)
+	nextID   int
+}
+
+// セルの位置
+type cellKey struct {
+	X, Y int
+}
+
+// 登録情報
+type spatialEntry struct {
+	id     int        // 登録順の通し番号
+	bounds gmath.Rect // AABB
+	min    cellKey    // 含まれるセルの範囲
+	max    cellKey
+}
+
+func NewSpatialHash[T comparable](cellSize float64) *SpatialHash[T] {
+	return &SpatialHash[T]{
+		CellSize: cellSize,
+		cells:    map[cellKey][]T{},
+		items:    map[T]*spatialEntry{},
+	}
+}
+
+// 登録数
+func (h *SpatialHash[T]) Len() int {
+	return len(h.order)
+}
+
+// 登録済みのアイテムを登録順に列挙する
+func (h *SpatialHash[T]) Each(f func(T)) {
+	for _, v := range h.order {
+		f(v)
+	}
+}
+
+// アイテムを登録する。登録済みの場合はUpdateと同じ
+func (h *SpatialHash[T]) Insert(item T, bounds gmath.Rect) {
+	if _, found := h.items[item]; found {
+		h.Update(item, bounds)
+		return
+	}
+
+	e := &spatialEntry{id: h.nextID, bounds: bounds}
+	e.min, e.max = h.cellRange(bounds)
+	h.nextID++
+	h.items[item] = e
+	h.order = append(h.order, item)
+	h.addCells(item, e)
+}
+
+// アイテムのAABBを更新する
+// 含まれるセルが変わった場合だけセルを登録しなおす
+func (h *SpatialHash[T]) Update(item T, bounds gmath.Rect) {
+	e, found := h.items[item]
+	if !found {
+		h.Insert(item, bounds)
+		return
+	}
+
+	e.bounds = bounds
+	mn, mx := h.cellRange(bounds)
+	if mn == e.min && mx == e.max {
+		return
+	}
+
+	h.removeCells(item, e)
+	e.min, e.max = mn, mx
+	h.addCells(item, e)
+}
+
+// アイテムを削除する
+func (h *SpatialHash[T]) Remove(item T) {
+	e, found := h.items[item]
+	if !found {
+		return
+	}
+
+	h.removeCells(item, e)
+	delete(h.items, item)
+	h.order = slices.DeleteFunc(h.order, func(v T) bool { return v == item })
+}
+
+// AABBが重なっている衝突候補のペアを列挙する
+// 同じペアは1回だけ、登録順が早いほうがaになる
+func (h *SpatialHash[T]) Pairs(f func(a, b T)) {
+	seen := map[T]struct{}{}
+	for _, a := range h.order {
+		ea := h.items[a]
+		clear(seen)
+		h.eachCell(ea, func(k cellKey) {
+			for _, b := range h.cells[k] {
+				eb := h.items[b]
+				if eb.id <= ea.id {
+					continue
+				}
+				if _, found := seen[b]; found {
+					continue
+				}
+				seen[b] = struct{}{}
+				if overlapRect(ea.bounds, eb.bounds) {
+					f(a, b)
+				}
+			}
+		})
+	}
+}
+
+// 矩形とAABBが重なっているアイテムを列挙する
+func (h *SpatialHash[T]) Query(bounds gmath.Rect, f func(T)) {
+	seen := map[T]struct{}{}
+	e := &spatialEntry{bounds: bounds}
+	e.min, e.max = h.cellRange(bounds)
+	h.eachCell(e, func(k cellKey) {
+		for _, v := range h.cells[k] {
+			if _, found := seen[v]; found {
+				continue
+			}
+			seen[v] = struct{}{}
+			if overlapRect(bounds, h.items[v].bounds) {
+				f(v)
+			}
+		}
+	})
+}
+
+// AABBが含まれるセルの範囲
+func (h *SpatialHash[T]) cellRange(b gmath.Rect) (cellKey, cellKey) {
+	return cellKey{X: int(math.Floor(b.Min.X / h.CellSize)), Y: int(math.Floor(b.Min.Y / h.CellSize))},
+		cellKey{X: int(math.Floor(b.Max.X / h.CellSize)), Y: int(math.Floor(b.Max.Y / h.CellSize))}
+}
+
+func (h *SpatialHash[T]) eachCell(e *spatialEntry, f func(cellKey)) {
+	for y := e.min.Y; y <= e.max.Y; y++ {
+		for x := e.min.X; x <= e.max.X; x++ {
+			f(cellKey{X: x, Y: y})
+		}
+	}
+}
+
+func (h *SpatialHash[T]) addCells(item T, e *spatialEntry) {
+	h.eachCell(e, func(k cellKey) {
+		h.cells[k] = append(h.cells[k], item)
+	})
+}
+
+func (h *SpatialHash[T]) removeCells(item T, e *spatialEntry) {
+	h.eachCell(e, func(k cellKey) {
+		s := slices.DeleteFunc(h.cells[k], func(v T) bool { return v == item })
+		if len(s) == 0 {
+			delete(h.cells, k)
+		} else {
+			h.cells[k] = s
+		}
+	})
+}
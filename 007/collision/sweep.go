@@ -0,0 +1,220 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 位置と回転角度と拡大率
+// 拡大率は多角形と円にだけ反映する
+type Transform struct {
+	Pos   gmath.Vec // 座標
+	Rad   gmath.Rad // 回転角度(ラジアン)
+	Scale gmath.Vec // 拡大率。ゼロ値の成分は等倍。PolygonとCircleだけが反映する
+}
+
+// 2つの姿勢の間を割合tで補間する
+func (t Transform) Lerp(to Transform, f float64) Transform {
+	return Transform{
+		Pos:   t.Pos.LinearInterpolate(to.Pos, f),
+		Rad:   t.Rad + (to.Rad-t.Rad)*gmath.Rad(f),
+		Scale: ScaleOf(t.Scale).LinearInterpolate(ScaleOf(to.Scale), f),
+	}
+}
+
+// 親の姿勢に子要素の相対的な姿勢を合成する
+// 親の拡大率が一様でない場合、回転した子要素の拡大率は近似になる
+func (t Transform) Compose(local Transform) Transform {
+	s := ScaleOf(t.Scale)
+	return Transform{
+		Pos:   t.Pos.Add(local.Pos.Mul(s).Rotated(t.Rad)),
+		Rad:   t.Rad + local.Rad,
+		Scale: s.Mul(ScaleOf(local.Scale)),
+	}
+}
+
+// 子要素を指定した位置と回転角度に配置する
+// Localsがある子要素は相対的な姿勢を合成した位置に置く
+func (c *Composit) SetTransform(t Transform) {
+	for i, d := range c.Collisions {
+		if i < len(c.Locals) {
+			place(d, t.Compose(c.Locals[i]))
+			continue
+		}
+		place(d, t)
+	}
+}
+
+// 衝突判定範囲を配置する
+// Capsule/Segment/Ellipse/AABBは拡大率を持たないので、Scaleを無視して等倍のまま置く
+func place(c Tester, t Transform) {
+	switch d := c.(type) {
+	case *Polygon:
+		d.Pos = t.Pos
+		d.Rad = t.Rad
+		d.Scale = t.Scale
+	case *Circle:
+		d.Pos = t.Pos
+		d.Rad = t.Rad
+		d.Scale = t.Scale
+	case *Capsule:
+		d.Pos = t.Pos
+		d.Rad = t.Rad
+	case *Segment:
+		d.Pos = t.Pos
+		d.Rad = t.Rad
+	case *Ellipse:
+		d.Pos = t.Pos
+		d.Rad = t.Rad
+	case *AABB:
+		d.Pos = t.Pos
+	case interface{ SetTransform(Transform) }:
+		// Compositや外部で定義した形状
+		d.SetTransform(t)
+	}
+}
+
+// 衝突判定範囲を複製する
+// 頂点集合は書き換えないので共有する
+func clone(c Tester) Tester {
+	switch d := c.(type) {
+	case *Polygon:
+		p := *d
+		return &p
+	case *Circle:
+		ci := *d
+		return &ci
+	case *Capsule:
+		cp := *d
+		return &cp
+	case *Segment:
+		sg := *d
+		return &sg
+	case *Ellipse:
+		e := *d
+		return &e
+	case *AABB:
+		b := *d
+		return &b
+	case *Composit:
+		co := Composit{
+			Collisions: make([]Tester, 0, len(d.Collisions)),
+			Operator:   d.Operator,
+			Locals:     d.Locals,
+			Filter:     d.Filter,
+		}
+		for _, v := range d.Collisions {
+			co.Collisions = append(co.Collisions, clone(v))
+		}
+		return &co
+	}
+
+	return c
+}
+
+// 基準座標から一番遠い点までの距離
+func boundingRadius(c Tester, pos gmath.Vec) float64 {
+	result := 0.0
+	switch d := scaledShape(c).(type) {
+	case *Circle:
+		result = d.Pos.DistanceTo(pos) + d.Radius
+	case *Composit:
+		for _, v := range d.Collisions {
+			result = max(result, boundingRadius(v, pos))
+		}
+	default:
+		for _, p := range Pieces(c) {
+			for _, v := range p {
+				result = max(result, v.DistanceTo(pos))
+			}
+		}
+	}
+
+	return result
+}
+
+// 一番薄い方向の幅
+// これより細かく刻んで移動させればすり抜けない
+func minExtent(c Tester) float64 {
+	result := math.Inf(1)
+	switch d := scaledShape(c).(type) {
+	case *Circle:
+		result = d.Radius * 2
+	case *Composit:
+		for _, v := range d.Collisions {
+			result = min(result, minExtent(v))
+		}
+	default:
+		for _, p := range Pieces(c) {
+			r := append(p, p[0])
+			for i := 0; i < len(r)-1; i++ {
+				min1, max1 := project(r, r[i+1].Sub(r[i]).Normalized())
+				result = min(result, max1-min1)
+			}
+		}
+	}
+
+	return result
+}
+
+// 移動中に最初に接触する時刻を求める(連続衝突判定)
+// aはfromAからtoAへ、bはfromBからtoBへ同時に移動するものとして、
+// 接触した移動の割合(0〜1)を返す。最初から重なっている場合は0を返す
+// 渡した衝突判定範囲は書き換えない
+func TimeOfImpact(a Tester, fromA, toA Transform, b Tester, fromB, toB Transform) (float64, bool) {
+	ca := clone(a)
+	cb := clone(b)
+
+	test := func(f float64) bool {
+		place(ca, fromA.Lerp(toA, f))
+		place(cb, fromB.Lerp(toB, f))
+		return ca.Test(cb)
+	}
+
+	total := motion(ca, fromA, toA) + motion(cb, fromB, toB)
+
+	// どちらかの一番薄い部分の半分ずつ刻む
+	return sweep(test, total, min(minExtent(ca), minExtent(cb))*0.5)
+}
+
+// 1ステップあたりの移動量の上限(平行移動分と回転分)
+func motion(c Tester, from, to Transform) float64 {
+	return from.Pos.DistanceTo(to.Pos) + math.Abs(float64(to.Rad-from.Rad))*boundingRadius(c, from.Pos)
+}
+
+// 移動の割合fで接触しているかを調べるtestを、移動量totalをstepずつ刻んで呼び、
+// 最初に接触する割合を求める
+// 刻みの数に上限を付けるとstepより大きく進んですり抜けるので、移動量に比例して増やす
+func sweep(test func(f float64) bool, total, step float64) (float64, bool) {
+	if test(0) {
+		return 0, true
+	}
+
+	steps := 1
+	if step > 0 && total > step && !math.IsInf(total, 0) {
+		steps = int(math.Ceil(total / step))
+	}
+
+	for i := 1; i <= steps; i++ {
+		f := float64(i) / float64(steps)
+		if !test(f) {
+			continue
+		}
+
+		// 接触したステップ内を二分探索で詰める
+		lo := float64(i-1) / float64(steps)
+		hi := f
+		for j := 0; j < 30; j++ {
+			m := (lo + hi) * 0.5
+			if test(m) {
+				hi = m
+			} else {
+				lo = m
+			}
+		}
+		return hi, true
+	}
+
+	return 1, false
+}
@@ -0,0 +1,24 @@
+package collision
+
+import (
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 細い線分を大きく動かしてもすり抜けない
+func TestTimeOfImpactThinSegment(t *testing.T) {
+	seg := &Segment{A: gmath.Vec{Y: -50}, B: gmath.Vec{Y: 50}, Thickness: 1}
+	wall := &Polygon{
+		Pos:      gmath.Vec{X: 2500},
+		Vertices: []gmath.Vec{{X: 0, Y: -100}, {X: 1, Y: -100}, {X: 1, Y: 100}, {X: 0, Y: 100}},
+	}
+
+	f, ok := TimeOfImpact(seg, Transform{}, Transform{Pos: gmath.Vec{X: 5000}}, wall, Transform{Pos: wall.Pos}, Transform{Pos: wall.Pos})
+	if !ok {
+		t.Fatal("segment tunnelled through the wall")
+	}
+	if want := 2499.5 / 5000; f < want-1e-6 || f > want+1e-6 {
+		t.Errorf("fraction = %v, want %v", f, want)
+	}
+}
@@ -0,0 +1,66 @@
+package collision
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/quasilyte/gmath"
+)
+
+var (
+	ErrDuplicateVertex  = errors.New("collision: polygon has duplicate vertices")
+	ErrNoArea           = errors.New("collision: polygon has no area")
+	ErrCounterClockwise = errors.New("collision: polygon vertices are counter-clockwise")
+	ErrNotConvex        = errors.New("collision: polygon is not convex")
+)
+
+// 凸型多角形の頂点集合として使えるかを調べる
+// 頂点が3個未満、重複、面積が無い、左回り、自己交差、凹型の場合はエラーを返す
+func ValidatePolygon(vs []gmath.Vec) error {
+	if len(vs) < 3 {
+		return fmt.Errorf("%w: got %d", ErrTooFewVertices, len(vs))
+	}
+
+	for i := range vs {
+		for j := i + 1; j < len(vs); j++ {
+			if vs[i].DistanceSquaredTo(vs[j]) < pieceEpsilon {
+				return fmt.Errorf("%w: vertex %d and %d at %v", ErrDuplicateVertex, i, j, vs[i])
+			}
+		}
+	}
+
+	area := polygonArea(vs)
+	if area > -pieceEpsilon && area < pieceEpsilon {
+		return ErrNoArea
+	}
+	if area < 0 {
+		return ErrCounterClockwise
+	}
+
+	if selfIntersecting(vs) {
+		return ErrSelfIntersecting
+	}
+
+	for i := range vs {
+		j := (i + 1) % len(vs)
+		if turn(vs[i], vs[j], vs[(i+2)%len(vs)]) < -pieceEpsilon {
+			return fmt.Errorf("%w: vertex %d at %v is concave", ErrNotConvex, j, vs[j])
+		}
+	}
+	return nil
+}
+
+// 頂点集合を検証して凸型多角形を作る
+// rewindがtrueの場合、左回りの頂点集合は並びを逆にして右回りに直す
+func NewPolygon(vs []gmath.Vec, rewind bool) (*Polygon, error) {
+	if rewind && len(vs) >= 3 && polygonArea(vs) < 0 {
+		vs = slices.Clone(vs)
+		slices.Reverse(vs)
+	}
+
+	if err := ValidatePolygon(vs); err != nil {
+		return nil, err
+	}
+	return &Polygon{Vertices: vs}, nil
+}
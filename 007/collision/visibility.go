@@ -0,0 +1,122 @@
+package collision
+
+import (
+	"math"
+	"slices"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 頂点の少し脇を通すレイの角度
+// 頂点をかすめた先の障害物や範囲の端まで届くようにする
+const visibilityEpsilon = 1e-5
+
+// 視点から見える範囲の多角形(右回り)を求める
+// 障害物の頂点と円の接点の方向を角度順に調べ、それぞれの方向にレイを飛ばして一番近い衝突点をつなぐ
+// 円は接点の間も何本かレイを飛ばして近似する。boundsは見える範囲の上限で、
+// 視点がboundsの外や障害物の内側にある場合はnilを返す
+func VisibilityPolygon(origin gmath.Vec, obstacles []Tester, bounds gmath.Rect) []gmath.Vec {
+	if !bounds.Contains(origin) {
+		return nil
+	}
+	for _, o := range obstacles {
+		if TestPoint(origin.X, origin.Y, o) {
+			return nil
+		}
+	}
+
+	// 調べる角度を集める
+	angles := make([]float64, 0, 32)
+	for _, v := range []gmath.Vec{bounds.Min, {X: bounds.Max.X, Y: bounds.Min.Y}, bounds.Max, {X: bounds.Min.X, Y: bounds.Max.Y}} {
+		angles = appendVertexAngles(angles, origin, v)
+	}
+	for _, o := range obstacles {
+		angles = visibilityAngles(angles, origin, o)
+	}
+	for i, a := range angles {
+		angles[i] = math.Remainder(a, 2*math.Pi)
+	}
+	slices.Sort(angles)
+
+	result := make([]gmath.Vec, 0, len(angles))
+	prev := math.Inf(-1)
+	for _, a := range angles {
+		if a-prev < 1e-9 {
+			continue
+		}
+		prev = a
+
+		d := gmath.Vec{X: math.Cos(a), Y: math.Sin(a)}
+		dist := rectExit(origin, d, bounds)
+		for _, o := range obstacles {
+			if h, ok := raycastTester(origin, d, dist, o); ok && h.Distance < dist {
+				dist = h.Distance
+			}
+		}
+		result = append(result, origin.Add(d.Mulf(dist)))
+	}
+	return result
+}
+
+// 頂点の方向と、その両脇の角度を追加する
+func appendVertexAngles(angles []float64, origin, v gmath.Vec) []float64 {
+	a := float64(v.Sub(origin).Angle())
+	return append(angles, a-visibilityEpsilon, a, a+visibilityEpsilon)
+}
+
+// 形状の見え方が変わる角度を追加する
+func visibilityAngles(angles []float64, origin gmath.Vec, c Tester) []float64 {
+	switch v := scaledShape(c).(type) {
+	case *Circle:
+		// 2本の接線の方向と、その間の円周
+		d := origin.DistanceTo(v.Pos)
+		if d <= v.Radius {
+			return angles
+		}
+		center := float64(v.Pos.Sub(origin).Angle())
+		half := math.Asin(v.Radius / d)
+		angles = append(angles,
+			center-half-visibilityEpsilon, center-half,
+			center+half, center+half+visibilityEpsilon,
+		)
+
+		// 見えている円周の中心角に応じて分割する
+		n := max(2, int(math.Ceil(float64(circleSegments)*(math.Pi-2*half)/(2*math.Pi))))
+		for i := 1; i < n; i++ {
+			angles = append(angles, center-half+2*half*float64(i)/float64(n))
+		}
+		return angles
+	case *Composit:
+		if v.Operator == CompositOr {
+			for _, d := range v.Collisions {
+				angles = visibilityAngles(angles, origin, d)
+			}
+			return angles
+		}
+	}
+
+	for _, p := range Pieces(c) {
+		for _, v := range p {
+			angles = appendVertexAngles(angles, origin, v)
+		}
+	}
+	return angles
+}
+
+// 矩形の内側から出たレイが矩形の外に出るまでの距離
+func rectExit(origin, d gmath.Vec, r gmath.Rect) float64 {
+	dist := math.Inf(1)
+	switch {
+	case d.X > 0:
+		dist = min(dist, (r.Max.X-origin.X)/d.X)
+	case d.X < 0:
+		dist = min(dist, (r.Min.X-origin.X)/d.X)
+	}
+	switch {
+	case d.Y > 0:
+		dist = min(dist, (r.Max.Y-origin.Y)/d.Y)
+	case d.Y < 0:
+		dist = min(dist, (r.Min.Y-origin.Y)/d.Y)
+	}
+	return dist
+}
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"image/color"
+
 	"myproject/control"
+	"myproject/nav"
 	"myproject/primitive"
 	"myproject/ui"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/quasilyte/gmath"
 )
 
 type Game struct {
-	objects  []primitive.Object
-	controls []ui.Control
-	dragMap  map[ui.TouchInfo]primitive.Object
-	dragObj  map[primitive.Object]struct{}
+	objects    []primitive.Object
+	controls   []ui.Control
+	dragMap    map[ui.TouchInfo]primitive.Object
+	dragObj    map[primitive.Object]struct{}
+	broadphase *primitive.Broadphase // 衝突判定の絞り込み
+	graph      *nav.Graph            // エージェントが障害物を避けるための可視グラフ
+	agent      *nav.Agent            // 何も無い場所をタッチすると、そこまで歩く
 }
 
 func newGame() *Game {
@@ -33,6 +40,25 @@ func (g *Game) Init() {
 	c4 := primitive.NewSimpleCircle(600, 100, 10)
 	g.objects = append(g.objects, c1, c2, c3, c4)
 
+	// ドラッグで動かせる障害物
+	r1 := primitive.NewRect(320, 240, 160, 40, 0.3)
+	r2 := primitive.NewRect(180, 160, 40, 140, 0)
+	g.objects = append(g.objects, r1, r2)
+
+	// 障害物を避けて歩くエージェント
+	g.graph = nav.NewGraph(8, gmath.Rect{Max: gmath.Vec{X: 640, Y: 480}})
+	for _, o := range g.objects {
+		g.graph.Add(o)
+	}
+	g.agent = nav.NewAgent(40, 40, g.graph)
+	g.objects = append(g.objects, g.agent)
+
+	// 各オブジェクトのUpdateで位置が反映される
+	g.broadphase = primitive.NewBroadphase(64)
+	for _, o := range g.objects {
+		g.broadphase.Add(o)
+	}
+
 	s1 := control.NewSlider(270, 440, 100, 40, "50", 24, ui.AdjustCenter, nil, func() {
 		g.controls[0].(*control.Slider).Slide() // 自分自身をアクセスする手段が無く苦肉の策
 	})
@@ -82,6 +108,7 @@ func (g *Game) Update() error {
 		if tinfo.IsJustPressed() {
 			x, y := tinfo.Pos()
 			// 押されたRectを探す
+			touched := false
 			for _, obj := range g.objects {
 
 				_, found := g.dragObj[obj]
@@ -89,9 +116,15 @@ func (g *Game) Update() error {
 					// ドラッグ中情報を保存
 					g.dragMap[tinfo] = obj
 					g.dragObj[obj] = struct{}{}
+					touched = true
 					break
 				}
 			}
+
+			// 何も無い場所はエージェントの目的地にする
+			if !touched {
+				g.agent.Target = gmath.Vec{X: float64(x), Y: float64(y)}
+			}
 		}
 	}
 
@@ -116,6 +149,17 @@ func (g *Game) Update() error {
 		r.Update()
 	}
 
+	// 障害物が動いたらエージェントの経路を求め直す
+	if g.graph.Update() {
+		g.agent.Replan()
+	}
+
+	// 衝突判定
+	g.broadphase.Collisions(func(o1, o2 primitive.Object) {
+		o1.SetFillColor(color.RGBA{0xff, 0xff, 0x00, 0xff})
+		o2.SetFillColor(color.RGBA{0xff, 0xff, 0x00, 0xff})
+	})
+
 	return nil
 }
 
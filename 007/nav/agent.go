@@ -0,0 +1,96 @@
+package nav
+
+import (
+	"image/color"
+
+	"myproject/collision"
+	"myproject/primitive"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/quasilyte/gmath"
+)
+
+var agentColor = color.RGBA{0xff, 0x80, 0x00, 0xff}
+
+// 障害物を避けて目的地まで歩くエージェント
+// 大きさはグラフの半径の円。センサーなので他の物体を押したりグラフの障害物になったりしない
+type Agent struct {
+	primitive.Base
+	Graph  *Graph
+	Target gmath.Vec // 目的地
+	Speed  float64   // 1フレームに進む距離
+
+	path    []gmath.Vec // 今の位置から目的地までの経路。たどり着けない場合は空
+	planned bool        // pathを求めた後に目的地や障害物が変わっていない
+	target  gmath.Vec   // pathを求めたときの目的地
+}
+
+func NewAgent(x, y float64, g *Graph) *Agent {
+	return &Agent{
+		Base: primitive.Base{
+			Pos:       gmath.Vec{X: x, Y: y},
+			FillColor: agentColor,
+			Composit: collision.Composit{
+				Collisions: []collision.Tester{&collision.Circle{Pos: gmath.Vec{X: x, Y: y}, Radius: g.Radius}},
+				Filter:     collision.Filter{Sensor: true},
+			},
+			HitMargin: 10,
+		},
+		Graph:  g,
+		Target: gmath.Vec{X: x, Y: y},
+		Speed:  2,
+	}
+}
+
+// 経路を求め直す
+// Graph.Updateで障害物が動いたことが分かったときに呼ぶ
+func (a *Agent) Replan() {
+	a.planned = false
+}
+
+// 経路に沿って進む
+func (a *Agent) Update() {
+	if !a.planned || a.target != a.Target {
+		a.path, _ = a.Graph.FindPath(a.Pos, a.Target)
+		a.target = a.Target
+		a.planned = true
+	}
+
+	step := a.Speed
+	for len(a.path) > 1 && step > 0 {
+		next := a.path[1]
+		d := a.Pos.DistanceTo(next)
+		if d <= step {
+			a.Pos = next
+			a.path = a.path[1:]
+			step -= d
+			continue
+		}
+		a.Pos = a.Pos.Add(next.Sub(a.Pos).Mulf(step / d))
+		step = 0
+	}
+	if len(a.path) > 0 {
+		a.path[0] = a.Pos
+	}
+
+	// Base.Updateで塗りつぶしの色が戻るので、エージェントの色にし直す
+	a.Base.Update()
+	a.FillColor = agentColor
+}
+
+// ドラッグでは回転させずに動かして、その位置から経路を求め直す
+func (a *Agent) Move(fx, fy, tx, ty float64) {
+	a.Pos.X += tx - fx
+	a.Pos.Y += ty - fy
+	a.Replan()
+}
+
+// 残りの経路を描いてからエージェントを描く
+func (a *Agent) Draw(screen *ebiten.Image) {
+	for i := 1; i < len(a.path); i++ {
+		p, q := a.path[i-1], a.path[i]
+		vector.StrokeLine(screen, float32(p.X), float32(p.Y), float32(q.X), float32(q.Y), 1, agentColor, true)
+	}
+	a.Base.Draw(screen)
+}
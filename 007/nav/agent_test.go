@@ -0,0 +1,46 @@
+package nav
+
+import (
+	"testing"
+
+	"myproject/primitive"
+
+	"github.com/quasilyte/gmath"
+)
+
+// エージェントは障害物に触れずに目的地までたどり着き、障害物が動けば経路を求め直す
+func TestAgentWalksAroundObstacle(t *testing.T) {
+	g := NewGraph(10, testBounds)
+	wall := primitive.NewRect(200, 200, 40, 300, 0)
+	g.Add(wall)
+
+	a := NewAgent(100, 200, g)
+	a.Target = gmath.Vec{X: 300, Y: 200}
+	for i := 0; i < 1000 && a.Pos != a.Target; i++ {
+		a.Update()
+		if wall.Test(&a.Composit) {
+			t.Fatalf("frame %d: agent at %v touches the wall", i, a.Pos)
+		}
+	}
+	if a.Pos != a.Target {
+		t.Fatalf("agent stopped at %v, want %v", a.Pos, a.Target)
+	}
+
+	// 壁を動かして下側の通り道を塞いでも、求め直した経路で戻ってくる
+	wall.Pos = gmath.Vec{X: 200, Y: 260}
+	wall.Update()
+	if !g.Update() {
+		t.Fatal("Update did not notice the moved wall")
+	}
+	a.Replan()
+	a.Target = gmath.Vec{X: 100, Y: 200}
+	for i := 0; i < 1000 && a.Pos != a.Target; i++ {
+		a.Update()
+		if wall.Test(&a.Composit) {
+			t.Fatalf("frame %d: agent at %v touches the moved wall", i, a.Pos)
+		}
+	}
+	if a.Pos != a.Target {
+		t.Fatalf("agent stopped at %v, want %v", a.Pos, a.Target)
+	}
+}
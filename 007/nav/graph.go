@@ -0,0 +1,263 @@
+package nav
+
+import (
+	"myproject/collision"
+	"myproject/primitive"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 経由点を障害物から離す距離
+// 経由点同士を結ぶ線分が障害物の境界に触れないようにする
+const nodeMargin = 1.0
+
+// 障害物を避けて移動するための可視グラフ
+// 障害物をエージェントの半径分広げ、その角を経由点にして、見通せる経由点同士をつなぐ
+// 障害物が動いた場合は、動いた範囲に関係する経由点とつながりだけを作り直す
+type Graph struct {
+	Radius    float64     // エージェントの半径
+	Bounds    gmath.Rect  // 移動できる範囲(プレイフィールド)
+	obstacles []*obstacle // 登録順の障害物
+}
+
+// 障害物として登録したオブジェクト
+type obstacle struct {
+	object    primitive.Object
+	transform collision.Transform // 最後に反映した姿勢
+	shape     *collision.Composit // 半径分広げた形状(凸型多角形のOr)
+	bounds    gmath.Rect          // shapeのAABB
+	nodes     []*node             // 角の経由点
+}
+
+// 経由点
+type node struct {
+	pos   gmath.Vec
+	owner *obstacle
+	free  bool              // 他の障害物や範囲の外に埋まっていない
+	links map[*node]float64 // 見通せる経由点と距離
+}
+
+// radiusはエージェントの半径、boundsは移動できる範囲
+func NewGraph(radius float64, bounds gmath.Rect) *Graph {
+	return &Graph{
+		Radius: radius,
+		Bounds: bounds,
+	}
+}
+
+// オブジェクトを障害物として登録する
+// センサーのオブジェクトは通り抜けられるので障害物にならない
+func (g *Graph) Add(o primitive.Object) {
+	if g.find(o) != nil {
+		return
+	}
+
+	ob := &obstacle{object: o}
+	g.obstacles = append(g.obstacles, ob)
+	g.reshape(ob)
+	g.refresh(ob.bounds, ob)
+}
+
+// 障害物の登録を解除する
+func (g *Graph) Remove(o primitive.Object) {
+	ob := g.find(o)
+	if ob == nil {
+		return
+	}
+
+	for i, v := range g.obstacles {
+		if v == ob {
+			g.obstacles = append(g.obstacles[:i], g.obstacles[i+1:]...)
+			break
+		}
+	}
+	detach(ob)
+	g.refresh(ob.bounds, nil)
+}
+
+// Base.Moveなどで動いた障害物を反映する
+// 姿勢が変わった障害物だけを作り直し、作り直した場合はtrueを返す
+func (g *Graph) Update() bool {
+	changed := false
+	for _, ob := range g.obstacles {
+		if transformOf(ob.object) == ob.transform {
+			continue
+		}
+
+		region := ob.bounds
+		detach(ob)
+		g.reshape(ob)
+		g.refresh(unionRect(region, ob.bounds), ob)
+		changed = true
+	}
+	return changed
+}
+
+func (g *Graph) find(o primitive.Object) *obstacle {
+	for _, ob := range g.obstacles {
+		if ob.object == o {
+			return ob
+		}
+	}
+	return nil
+}
+
+// オブジェクトの今の姿勢
+func transformOf(o primitive.Object) collision.Transform {
+	if t, ok := o.(interface{ GetTransform() collision.Transform }); ok {
+		return t.GetTransform()
+	}
+	return collision.Transform{Pos: o.GetPos()}
+}
+
+// 今の姿勢で広げた形状と経由点を作る
+func (g *Graph) reshape(ob *obstacle) {
+	ob.transform = transformOf(ob.object)
+	ob.shape = &collision.Composit{Operator: collision.CompositOr}
+	ob.bounds = gmath.Rect{}
+	ob.nodes = nil
+
+	c := ob.object.GetComposit()
+	if c.Filter.Sensor {
+		return
+	}
+
+	// Updateの前に動かされていても今の姿勢で作る
+	c.SetTransform(ob.transform)
+
+	// 断片ごとに広げる。断片はグローバル座標なので拡大率に関係なく半径分広がる
+	first := true
+	for _, p := range collision.Pieces(c) {
+		vs := collision.OffsetPolygon(p, g.Radius, collision.JoinMiter)
+		if len(vs) < 3 {
+			continue
+		}
+		poly := &collision.Polygon{Vertices: vs}
+		ob.shape.Collisions = append(ob.shape.Collisions, poly)
+		if first {
+			ob.bounds = poly.Bounds()
+			first = false
+		} else {
+			ob.bounds = unionRect(ob.bounds, poly.Bounds())
+		}
+
+		for _, v := range collision.OffsetPolygon(p, g.Radius+nodeMargin, collision.JoinMiter) {
+			ob.nodes = append(ob.nodes, &node{pos: v, owner: ob, links: map[*node]float64{}})
+		}
+	}
+}
+
+// 障害物の経由点とのつながりを全て切る
+func detach(ob *obstacle) {
+	for _, n := range ob.nodes {
+		unlinkAll(n)
+	}
+}
+
+func unlinkAll(n *node) {
+	for m := range n.links {
+		delete(m.links, n)
+	}
+	clear(n.links)
+}
+
+// regionの中で変わった可能性のある経由点とつながりを調べ直す
+// addedは作り直した障害物で、その経由点は全てのつながりを調べる
+func (g *Graph) refresh(region gmath.Rect, added *obstacle) {
+	nodes := g.nodes()
+	for _, n := range nodes {
+		if n.owner != added && !inRect(region, n.pos) {
+			continue
+		}
+		n.free = g.pointFree(n.pos)
+		if !n.free {
+			unlinkAll(n)
+		}
+	}
+
+	for i, a := range nodes {
+		if !a.free {
+			continue
+		}
+		for _, b := range nodes[i+1:] {
+			if !b.free || a.owner != added && b.owner != added && !overlapRect(segmentRect(a.pos, b.pos), region) {
+				continue
+			}
+			if g.visible(a.pos, b.pos) {
+				d := a.pos.DistanceTo(b.pos)
+				a.links[b] = d
+				b.links[a] = d
+			} else {
+				delete(a.links, b)
+				delete(b.links, a)
+			}
+		}
+	}
+}
+
+// 登録順の全ての経由点
+func (g *Graph) nodes() []*node {
+	var result []*node
+	for _, ob := range g.obstacles {
+		result = append(result, ob.nodes...)
+	}
+	return result
+}
+
+// エージェントの中心を置けるか
+func (g *Graph) pointFree(p gmath.Vec) bool {
+	r := g.Radius
+	if p.X < g.Bounds.Min.X+r || p.X > g.Bounds.Max.X-r || p.Y < g.Bounds.Min.Y+r || p.Y > g.Bounds.Max.Y-r {
+		return false
+	}
+	for _, ob := range g.obstacles {
+		if len(ob.shape.Collisions) > 0 && inRect(ob.bounds, p) && collision.TestPoint(p.X, p.Y, ob.shape) {
+			return false
+		}
+	}
+	return true
+}
+
+// 2点を結ぶ線分が広げた障害物に遮られていないか
+// 両端は障害物の外側にあるものとする
+func (g *Graph) visible(a, b gmath.Vec) bool {
+	d := b.Sub(a)
+	l := d.Len()
+	if l == 0 {
+		return true
+	}
+
+	r := segmentRect(a, b)
+	for _, ob := range g.obstacles {
+		if len(ob.shape.Collisions) == 0 || !overlapRect(r, ob.bounds) {
+			continue
+		}
+		if h, ok := ob.shape.Raycast(a, d, l); ok && h.Distance < l-1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func segmentRect(a, b gmath.Vec) gmath.Rect {
+	return gmath.Rect{
+		Min: gmath.Vec{X: min(a.X, b.X), Y: min(a.Y, b.Y)},
+		Max: gmath.Vec{X: max(a.X, b.X), Y: max(a.Y, b.Y)},
+	}
+}
+
+func unionRect(a, b gmath.Rect) gmath.Rect {
+	return gmath.Rect{
+		Min: gmath.Vec{X: min(a.Min.X, b.Min.X), Y: min(a.Min.Y, b.Min.Y)},
+		Max: gmath.Vec{X: max(a.Max.X, b.Max.X), Y: max(a.Max.Y, b.Max.Y)},
+	}
+}
+
+// 境界上も含めて点が矩形の中にあるか
+func inRect(r gmath.Rect, p gmath.Vec) bool {
+	return r.Min.X <= p.X && p.X <= r.Max.X && r.Min.Y <= p.Y && p.Y <= r.Max.Y
+}
+
+func overlapRect(a, b gmath.Rect) bool {
+	return a.Min.X <= b.Max.X && b.Min.X <= a.Max.X && a.Min.Y <= b.Max.Y && b.Min.Y <= a.Max.Y
+}
@@ -0,0 +1,160 @@
+package nav
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"myproject/primitive"
+
+	"github.com/quasilyte/gmath"
+)
+
+var testBounds = gmath.Rect{Max: gmath.Vec{X: 400, Y: 400}}
+
+func pathLength(path []gmath.Vec) float64 {
+	l := 0.0
+	for i := 1; i < len(path); i++ {
+		l += path[i-1].DistanceTo(path[i])
+	}
+	return l
+}
+
+// 1つの障害物を角に沿って回り込む
+func TestFindPathAroundObstacle(t *testing.T) {
+	g := NewGraph(10, testBounds)
+	g.Add(primitive.NewRect(200, 200, 100, 100, 0))
+
+	from := gmath.Vec{X: 100, Y: 200}
+	to := gmath.Vec{X: 300, Y: 200}
+	path, ok := g.FindPath(from, to)
+	if !ok {
+		t.Fatal("no path around the obstacle")
+	}
+
+	// 半径と余白の分だけ広げた角(139, 139)と(261, 139)、または下側の角を通る
+	want := 2*math.Hypot(39, 61) + 122
+	if len(path) != 4 || math.Abs(pathLength(path)-want) > 1e-9 {
+		t.Errorf("path = %v with length %v, want 4 points with length %v", path, pathLength(path), want)
+	}
+	if path[0] != from || path[len(path)-1] != to {
+		t.Errorf("path = %v, want it to start at %v and end at %v", path, from, to)
+	}
+
+	// 見通せる場合は直線
+	path, ok = g.FindPath(gmath.Vec{X: 100, Y: 50}, gmath.Vec{X: 300, Y: 50})
+	if !ok || len(path) != 2 {
+		t.Errorf("path = %v, %v, want a straight line", path, ok)
+	}
+}
+
+func TestFindPathFails(t *testing.T) {
+	g := NewGraph(10, testBounds)
+	g.Add(primitive.NewRect(200, 200, 100, 100, 0))
+	free := gmath.Vec{X: 50, Y: 50}
+
+	tests := []struct {
+		name     string
+		from, to gmath.Vec
+	}{
+		{"start inside the obstacle", gmath.Vec{X: 200, Y: 200}, free},
+		{"goal inside the obstacle", free, gmath.Vec{X: 200, Y: 200}},
+		{"start within the radius of the obstacle", gmath.Vec{X: 145, Y: 200}, free},
+		{"goal within the radius of the bounds", free, gmath.Vec{X: 395, Y: 200}},
+	}
+	for _, tt := range tests {
+		if path, ok := g.FindPath(tt.from, tt.to); ok {
+			t.Errorf("%s: FindPath = %v, want false", tt.name, path)
+		}
+	}
+
+	// 範囲の端から端までの壁で分けられていると、たどり着けない
+	g.Add(primitive.NewRect(350, 200, 20, 400, 0))
+	if path, ok := g.FindPath(free, gmath.Vec{X: 380, Y: 200}); ok {
+		t.Errorf("FindPath across the wall = %v, want false", path)
+	}
+}
+
+// センサーは通り抜けられる
+func TestFindPathIgnoresSensor(t *testing.T) {
+	g := NewGraph(10, testBounds)
+	sensor := primitive.NewRect(200, 200, 100, 100, 0)
+	sensor.Filter.Sensor = true
+	g.Add(sensor)
+
+	path, ok := g.FindPath(gmath.Vec{X: 100, Y: 200}, gmath.Vec{X: 300, Y: 200})
+	if !ok || len(path) != 2 {
+		t.Errorf("path = %v, %v, want a straight line through the sensor", path, ok)
+	}
+}
+
+// 経由点の位置とつながりを登録順の番号で比べられる形にする
+type nodeState struct {
+	pos   gmath.Vec
+	free  bool
+	links map[int]float64
+}
+
+func graphState(g *Graph) []nodeState {
+	nodes := g.nodes()
+	index := map[*node]int{}
+	for i, n := range nodes {
+		index[n] = i
+	}
+
+	result := make([]nodeState, 0, len(nodes))
+	for _, n := range nodes {
+		s := nodeState{pos: n.pos, free: n.free, links: map[int]float64{}}
+		for m, d := range n.links {
+			s.links[index[m]] = d
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// 障害物を動かして作り直した部分が、最初から作ったグラフと同じになる
+func TestGraphUpdateMatchesRebuild(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var obstacles []*primitive.Base
+	for i := 0; i < 6; i++ {
+		obstacles = append(obstacles, primitive.NewRect(40+r.Float64()*320, 40+r.Float64()*320, 20+r.Float64()*40, 20+r.Float64()*40, r.Float64()))
+	}
+
+	g := NewGraph(8, testBounds)
+	for _, o := range obstacles {
+		g.Add(o)
+	}
+
+	for step := 0; step < 30; step++ {
+		o := obstacles[r.Intn(len(obstacles))]
+		fx, fy := o.Pos.X+5, o.Pos.Y
+		o.Move(fx, fy, fx+r.Float64()*80-40, fy+r.Float64()*80-40)
+		if !g.Update() {
+			t.Fatalf("step %d: Update did not notice the move", step)
+		}
+		if g.Update() {
+			t.Fatalf("step %d: Update rebuilt without a move", step)
+		}
+
+		fresh := NewGraph(8, testBounds)
+		for _, o := range obstacles {
+			fresh.Add(o)
+		}
+
+		got, want := graphState(g), graphState(fresh)
+		if len(got) != len(want) {
+			t.Fatalf("step %d: %d nodes, want %d", step, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].pos != want[i].pos || got[i].free != want[i].free || len(got[i].links) != len(want[i].links) {
+				t.Fatalf("step %d: node %d = %+v, want %+v", step, i, got[i], want[i])
+			}
+			for j, d := range want[i].links {
+				if gd, found := got[i].links[j]; !found || gd != d {
+					t.Fatalf("step %d: link %d-%d = %v, %v, want %v", step, i, j, gd, found, d)
+				}
+			}
+		}
+	}
+}
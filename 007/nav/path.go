@@ -0,0 +1,131 @@
+package nav
+
+import (
+	"container/heap"
+
+	"github.com/quasilyte/gmath"
+)
+
+// fromからtoまで障害物を避けて移動する経路を求める
+// 経路は始点と終点を含む経由点の列で、見通せる経由点を飛ばして短くしてある
+// 始点か終点にエージェントを置けない場合や、たどり着けない場合はfalseを返す
+func (g *Graph) FindPath(from, to gmath.Vec) ([]gmath.Vec, bool) {
+	if !g.pointFree(from) || !g.pointFree(to) {
+		return nil, false
+	}
+	if g.visible(from, to) {
+		return []gmath.Vec{from, to}, true
+	}
+
+	// 始点と終点は一時的な経由点にして、グラフは書き換えない
+	start := &node{pos: from, free: true, links: map[*node]float64{}}
+	goal := &node{pos: to, free: true}
+	goalLinks := map[*node]float64{}
+	for _, n := range g.nodes() {
+		if !n.free {
+			continue
+		}
+		if g.visible(from, n.pos) {
+			start.links[n] = from.DistanceTo(n.pos)
+		}
+		if g.visible(n.pos, to) {
+			goalLinks[n] = n.pos.DistanceTo(to)
+		}
+	}
+
+	nodes := search(start, goal, goalLinks)
+	if nodes == nil {
+		return nil, false
+	}
+
+	path := make([]gmath.Vec, 0, len(nodes))
+	for _, n := range nodes {
+		path = append(path, n.pos)
+	}
+	return g.smooth(path), true
+}
+
+// A*で始点から終点までの経由点の列を求める
+// 終点へのつながりはgoalLinksで渡す
+func search(start, goal *node, goalLinks map[*node]float64) []*node {
+	cost := map[*node]float64{start: 0}
+	prev := map[*node]*node{}
+	closed := map[*node]bool{}
+	open := &pathQueue{{node: start, f: start.pos.DistanceTo(goal.pos)}}
+
+	for open.Len() > 0 {
+		n := heap.Pop(open).(pathItem).node
+		if closed[n] {
+			continue
+		}
+		if n == goal {
+			var result []*node
+			for ; n != nil; n = prev[n] {
+				result = append(result, n)
+			}
+			for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+				result[i], result[j] = result[j], result[i]
+			}
+			return result
+		}
+		closed[n] = true
+
+		visit := func(m *node, d float64) {
+			c := cost[n] + d
+			if old, found := cost[m]; found && old <= c {
+				return
+			}
+			cost[m] = c
+			prev[m] = n
+			heap.Push(open, pathItem{node: m, f: c + m.pos.DistanceTo(goal.pos)})
+		}
+		for m, d := range n.links {
+			visit(m, d)
+		}
+		if d, found := goalLinks[n]; found {
+			visit(goal, d)
+		}
+	}
+	return nil
+}
+
+// 見通せる経由点を飛ばして経路を縮める(紐を引っ張るように)
+// ファネル(Simple Stupid Funnel)ではなく、前から順に一番遠くの見通せる経由点へ進むだけの処理
+// ファネルはナビメッシュの多角形の列の中で経路をまっすぐにするためのもので、ここでは必要ない
+// 可視グラフの経由点は障害物の角そのもので、つながりは全て見通せる線分なので、
+// A*の結果はすでに角に沿って張った紐と同じ最短経路になっている
+// ここでは一直線に並んだ経由点など、飛ばしても同じ長さになる経由点を取り除く
+func (g *Graph) smooth(path []gmath.Vec) []gmath.Vec {
+	result := []gmath.Vec{path[0]}
+	for i := 0; i < len(path)-1; {
+		// iから見通せる一番遠い経由点まで進む
+		j := len(path) - 1
+		for j > i+1 && !g.visible(path[i], path[j]) {
+			j--
+		}
+		result = append(result, path[j])
+		i = j
+	}
+	return result
+}
+
+// A*の候補
+type pathItem struct {
+	node *node
+	f    float64 // 始点からのコストと終点までの推定距離の和
+}
+
+// fが小さい順に取り出すヒープ
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].f < q[j].f }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathItem)) }
+
+func (q *pathQueue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -34,8 +34,18 @@ type Object interface {
 type Base struct {
 	Pos                gmath.Vec
 	Rad                gmath.Rad
+	Scale              gmath.Vec // 拡大率。ゼロ値の成分は等倍。Capsule/Segment/Ellipse/AABBの形状は拡大しない
 	FillColor          color.Color
-	collision.Composit // 処理の簡素化のためにComposit専用とする
+	CentroidPivot      bool    // Moveで重心を回転の中心にする
+	HitMargin          float64 // タッチ操作の判定だけを広げる距離。描画や物体同士の判定には影響しない
+	collision.Composit         // 処理の簡素化のためにComposit専用とする
+
+	broadphases []*Broadphase // 登録されているブロードフェーズ。Updateで位置を反映する
+	owner       Object        // ブロードフェーズに登録したオブジェクト(Baseを埋め込んだ型)
+
+	hitShape     collision.Tester    // HitMarginで広げた形状のキャッシュ
+	hitTransform collision.Transform // hitShapeを作ったときの姿勢
+	hitMargin    float64             // hitShapeを作ったときのHitMargin
 }
 
 func NewPolygon(x, y, r float64, vs []gmath.Vec) *Base {
@@ -59,53 +69,115 @@ func NewPolygon(x, y, r float64, vs []gmath.Vec) *Base {
 	}
 }
 
+// 頂点集合を検証してから凸型多角形を作る
+// レベルデータなど外から読み込んだ頂点集合に使う。rewindがtrueなら左回りを右回りに直す
+func NewPolygonChecked(x, y, r float64, vs []gmath.Vec, rewind bool) (*Base, error) {
+	c, err := collision.NewPolygon(vs, rewind)
+	if err != nil {
+		return nil, err
+	}
+	return NewPolygon(x, y, r, c.Vertices), nil
+}
+
 // 特殊な形状を除いて、基本的には衝突判定の範囲を描画する
 func (b *Base) Draw(screen *ebiten.Image) {
-	for _, c := range b.Collisions {
-		switch d := c.(type) {
-		case *collision.Polygon: // 凸型多角形の描画
-			var path vector.Path
-
-			// 全座標の計算とパス設定
-			pos := d.Vertices[0].Sub(d.Origin).Rotated(d.Rad).Add(d.Origin).Add(d.Pos)
-			path.MoveTo(float32(pos.X), float32(pos.Y))
-
-			for i := 1; i < len(d.Vertices); i++ {
-				v := d.Vertices[i].Sub(d.Origin).Rotated(d.Rad).Add(d.Origin).Add(d.Pos)
-				path.LineTo(float32(v.X), float32(v.Y))
-			}
-			path.Close()
-
-			// 描画用頂点情報作成
-			var vertices []ebiten.Vertex = []ebiten.Vertex{}
-			var indices []uint16 = []uint16{}
-			r, g, b, _ := b.FillColor.RGBA()
-			vertices, indices = path.AppendVerticesAndIndicesForFilling(vertices[:0], indices[:0])
-			for i := range vertices {
-				vertices[i].SrcX = 1
-				vertices[i].SrcY = 1
-				vertices[i].ColorR = float32(r) / float32(0xff)
-				vertices[i].ColorG = float32(g) / float32(0xff)
-				vertices[i].ColorB = float32(b) / float32(0xff)
-				vertices[i].ColorA = 1
-			}
-
-			op := &ebiten.DrawTrianglesOptions{}
-			op.AntiAlias = true
-			op.FillRule = ebiten.FillRuleNonZero
-			screen.DrawTriangles(vertices, indices, whitePixel, op)
-		case *collision.Circle: // 円の描画
-			vector.DrawFilledCircle(screen, float32(d.Pos.X), float32(d.Pos.Y), float32(d.Radius), b.FillColor, true)
+	drawTester(screen, &b.Composit, b.FillColor)
+}
+
+// 衝突判定範囲の描画
+// Orの複合形状は子要素ごとに、それ以外の複合形状は組み合わせた領域を描画する
+func drawTester(screen *ebiten.Image, t collision.Tester, clr color.Color) {
+	switch d := t.(type) {
+	case *collision.Polygon: // 凸型多角形の描画
+		drawPolygons(screen, collision.Pieces(d), clr)
+	case *collision.Circle: // 円の描画
+		rx, ry := d.Radii()
+		if rx != ry { // 楕円になる場合は多角形で近似する
+			drawPolygons(screen, collision.Pieces(d), clr)
+			return
+		}
+		vector.DrawFilledCircle(screen, float32(d.Pos.X), float32(d.Pos.Y), float32(rx), clr, true)
+	case *collision.Composit:
+		if d.Operator != collision.CompositOr {
+			drawPolygons(screen, collision.Pieces(d), clr)
+			return
+		}
+		for _, c := range d.Collisions {
+			drawTester(screen, c, clr)
 		}
+	default: // それ以外の形状は多角形で近似して描画
+		drawPolygons(screen, collision.Pieces(d), clr)
 	}
 }
 
+// 多角形の集合を1つのパスにして塗りつぶす
+// clrのアルファ値も反映するので、半透明の色なら下の形状が透けて見える
+func drawPolygons(screen *ebiten.Image, polygons [][]gmath.Vec, clr color.Color) {
+	var path vector.Path
+
+	// 全座標のパス設定
+	for _, vs := range polygons {
+		path.MoveTo(float32(vs[0].X), float32(vs[0].Y))
+		for _, v := range vs[1:] {
+			path.LineTo(float32(v.X), float32(v.Y))
+		}
+		path.Close()
+	}
+
+	// 描画用頂点情報作成
+	var vertices []ebiten.Vertex = []ebiten.Vertex{}
+	var indices []uint16 = []uint16{}
+	// RGBAはアルファ乗算済みの0〜0xffffの値なので、そのまま頂点の色にする
+	r, g, b, a := clr.RGBA()
+	vertices, indices = path.AppendVerticesAndIndicesForFilling(vertices[:0], indices[:0])
+	for i := range vertices {
+		vertices[i].SrcX = 1
+		vertices[i].SrcY = 1
+		vertices[i].ColorR = float32(r) / float32(0xffff)
+		vertices[i].ColorG = float32(g) / float32(0xffff)
+		vertices[i].ColorB = float32(b) / float32(0xffff)
+		vertices[i].ColorA = float32(a) / float32(0xffff)
+	}
+
+	op := &ebiten.DrawTrianglesOptions{}
+	op.AntiAlias = true
+	op.FillRule = ebiten.FillRuleNonZero
+	screen.DrawTriangles(vertices, indices, whitePixel, op)
+}
+
 // 座標(x, y)がRectの中にあるかどうかをチェックする
 func (b *Base) CheckPoint(x, y float64) bool {
+	// 判定を広げる場合は丸く膨らませた形状で判定する
+	if b.HitMargin != 0 {
+		o := b.hitOffset()
+		return o != nil && collision.TestPoint(x, y, o)
+	}
+
 	// 点と凸型多角形の衝突判定
 	return collision.TestPointComposit(x, y, &b.Composit)
 }
 
+// HitMarginで丸く膨らませた形状
+// 姿勢とHitMarginが変わったときだけ作り直す。子要素を差し替えた場合は姿勢を変えるまで反映されない
+func (b *Base) hitOffset() collision.Tester {
+	t := b.GetTransform()
+	if b.hitMargin == b.HitMargin && b.hitTransform == t {
+		return b.hitShape
+	}
+
+	// Updateの前に動かされていても今の姿勢で作る
+	b.SetTransform(t)
+	b.hitShape = collision.Offset(&b.Composit, b.HitMargin, collision.JoinRound)
+	b.hitTransform = t
+	b.hitMargin = b.HitMargin
+	return b.hitShape
+}
+
+// タッチ操作の判定を広げる距離
+func (b *Base) GetHitMargin() float64 {
+	return b.HitMargin
+}
+
 // ベクトル(x, y)を正規化する
 func normalize(x, y float64) (float64, float64) {
 	len := math.Hypot(x, y)
@@ -117,32 +189,51 @@ func normalize(x, y float64) (float64, float64) {
 }
 
 // 情報を更新する
+// ブロードフェーズに登録されていれば、動いた位置をそのまま反映する
 func (b *Base) Update() {
-	for _, c := range b.Collisions {
-		switch d := c.(type) {
-		case *collision.Polygon:
-			d.Pos = b.Pos
-			d.Rad = b.Rad
-		case *collision.Circle:
-			d.Pos = b.Pos
-		}
+	b.SetTransform(b.GetTransform())
+	for _, p := range b.broadphases {
+		p.hash.Update(b.owner, b.Bounds())
 	}
 	b.FillColor = color.RGBA{0x00, 0xff, 0xff, 0xff}
 }
 
 // 重なっているかどうかをチェックする
+// 絞り込み条件で衝突しない組み合わせは判定しない
 func (b *Base) TestCollinsion(o Object) bool {
-	return b.Test(o.GetComposit())
+	c := o.GetComposit()
+	if !b.Filter.ShouldCollide(c.Filter) {
+		return false
+	}
+	return b.Test(c)
+}
+
+// 今の位置・回転角度・拡大率
+func (b *Base) GetTransform() collision.Transform {
+	return collision.Transform{Pos: b.Pos, Rad: b.Rad, Scale: b.Scale}
 }
 
 // マウスドラッグで掴んだ場所をfx,fyからtx,tyまで移動させる
+// CentroidPivotがtrueなら、Posの代わりに重心が掴んだ場所に引っ張られるように回転する
 func (b *Base) Move(fx, fy, tx, ty float64) {
-	oldAngle := math.Atan2(b.Pos.Y-fy, b.Pos.X-fx)
-	len := math.Hypot(fx-b.Pos.X, fy-b.Pos.Y)
-	vx, vy := normalize(b.Pos.X-tx, b.Pos.Y-ty)
-	b.Rad = b.Rad + gmath.Rad(math.Atan2(vy, vx)-oldAngle)
-	b.Pos.X = tx + vx*len
-	b.Pos.Y = ty + vy*len
+	pivot := b.Pos
+	if b.CentroidPivot {
+		b.SetTransform(b.GetTransform())
+		if m := b.Composit.Mass(1); m.Area > 0 {
+			pivot = m.Centroid
+		}
+	}
+
+	oldAngle := math.Atan2(pivot.Y-fy, pivot.X-fx)
+	len := math.Hypot(fx-pivot.X, fy-pivot.Y)
+	vx, vy := normalize(pivot.X-tx, pivot.Y-ty)
+	d := gmath.Rad(math.Atan2(vy, vx) - oldAngle)
+
+	// 回転の中心からPosまでのずれも一緒に回す
+	offset := b.Pos.Sub(pivot).Rotated(d)
+	b.Rad = b.Rad + d
+	b.Pos.X = tx + vx*len + offset.X
+	b.Pos.Y = ty + vy*len + offset.Y
 }
 
 func (b *Base) SetFillColor(c color.Color) {
@@ -0,0 +1,74 @@
+package primitive
+
+import (
+	"slices"
+
+	"myproject/collision"
+)
+
+// オブジェクト同士の衝突判定を空間ハッシュで絞り込む
+// 全ペアを総当たりせずに、近くにあるペアだけ詳細な判定をする
+type Broadphase struct {
+	hash *collision.SpatialHash[Object]
+}
+
+func NewBroadphase(cellSize float64) *Broadphase {
+	return &Broadphase{
+		hash: collision.NewSpatialHash[Object](cellSize),
+	}
+}
+
+// Updateで位置をブロードフェーズに反映するオブジェクト
+// Baseを埋め込んだオブジェクトが実装している
+type broadphaseTracker interface {
+	trackBroadphase(b *Broadphase, o Object)
+	untrackBroadphase(b *Broadphase)
+}
+
+// オブジェクトを登録する
+// Baseを埋め込んだオブジェクトは、以降Base.Updateのたびに位置が反映される
+func (b *Broadphase) Add(o Object) {
+	b.hash.Insert(o, o.GetComposit().Bounds())
+	if t, ok := o.(broadphaseTracker); ok {
+		t.trackBroadphase(b, o)
+	}
+}
+
+// オブジェクトを削除する
+func (b *Broadphase) Remove(o Object) {
+	b.hash.Remove(o)
+	if t, ok := o.(broadphaseTracker); ok {
+		t.untrackBroadphase(b)
+	}
+}
+
+// Baseを埋め込んでいないオブジェクトの位置を反映する
+// そういったオブジェクトがある場合は、各オブジェクトのUpdateの後に呼ぶ。セルをまたいだオブジェクトだけ登録しなおされる
+func (b *Broadphase) Update() {
+	b.hash.Each(func(o Object) {
+		if _, ok := o.(broadphaseTracker); ok {
+			return
+		}
+		b.hash.Update(o, o.GetComposit().Bounds())
+	})
+}
+
+func (b *Base) trackBroadphase(p *Broadphase, o Object) {
+	if !slices.Contains(b.broadphases, p) {
+		b.broadphases = append(b.broadphases, p)
+	}
+	b.owner = o
+}
+
+func (b *Base) untrackBroadphase(p *Broadphase) {
+	b.broadphases = slices.DeleteFunc(b.broadphases, func(v *Broadphase) bool { return v == p })
+}
+
+// 重なっているオブジェクトのペアを列挙する
+func (b *Broadphase) Collisions(f func(o1, o2 Object)) {
+	b.hash.Pairs(func(o1, o2 Object) {
+		if o1.TestCollinsion(o2) {
+			f(o1, o2)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package primitive
+
+import (
+	"image/color"
+
+	"myproject/collision"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/quasilyte/gmath"
+)
+
+var lightColor = color.RGBA{0xff, 0xff, 0x00, 0xff}
+
+// 光源
+// 障害物に遮られずに見える範囲を照らす。光源自体も小さな円なのでドラッグで動かせる
+type Light struct {
+	Base
+	Obstacles []Object    // 光を遮るオブジェクト。光源自身とセンサーは無視する
+	Bounds    gmath.Rect  // 照らす範囲の上限
+	LitColor  color.Color // 照らした範囲の色
+}
+
+func NewLight(x, y float64, obstacles []Object) *Light {
+	return &Light{
+		Base: Base{
+			Pos:       gmath.Vec{X: x, Y: y},
+			FillColor: lightColor,
+			HitMargin: 10,
+			Composit: collision.Composit{
+				Collisions: []collision.Tester{&collision.Circle{Radius: 8}},
+				Filter:     collision.Filter{Sensor: true},
+			},
+		},
+		Obstacles: obstacles,
+		Bounds:    gmath.Rect{Max: gmath.Vec{X: 640, Y: 480}},
+		LitColor:  color.RGBA{0x40, 0x40, 0x20, 0x40},
+	}
+}
+
+// Base.Updateで塗りつぶしの色が戻るので、光源の色にし直す
+func (l *Light) Update() {
+	l.Base.Update()
+	l.FillColor = lightColor
+}
+
+// 照らした範囲を半透明で描いてから光源を描く
+// ドラッグ中の障害物にも追従するように、描画のたびに求め直す
+func (l *Light) Draw(screen *ebiten.Image) {
+	if lit := l.Lit(); len(lit) >= 3 {
+		drawPolygons(screen, [][]gmath.Vec{lit}, l.LitColor)
+	}
+	l.Base.Draw(screen)
+}
+
+// 今の位置から照らされる範囲(グローバル座標、右回り)
+// 障害物の内側にある場合はnilを返す
+func (l *Light) Lit() []gmath.Vec {
+	obstacles := make([]collision.Tester, 0, len(l.Obstacles))
+	for _, o := range l.Obstacles {
+		c := o.GetComposit()
+		if c == &l.Composit || !c.Filter.Hits(0) {
+			continue
+		}
+		obstacles = append(obstacles, c)
+	}
+	return collision.VisibilityPolygon(l.Pos, obstacles, l.Bounds)
+}
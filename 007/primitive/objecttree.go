@@ -0,0 +1,105 @@
+package primitive
+
+import (
+	"myproject/collision"
+
+	"github.com/quasilyte/gmath"
+)
+
+// オブジェクトを動的AABB木で管理して、点・矩形・レイの問い合わせを高速にする
+type ObjectTree struct {
+	tree *collision.AABBTree[Object]
+	ids  map[Object]int
+}
+
+// marginはAABBに付ける余白。大きいほど木の組み替えが減る
+func NewObjectTree(margin float64) *ObjectTree {
+	return &ObjectTree{
+		tree: collision.NewAABBTree[Object](margin),
+		ids:  map[Object]int{},
+	}
+}
+
+// オブジェクトを登録する
+func (t *ObjectTree) Add(o Object) {
+	if _, found := t.ids[o]; found {
+		return
+	}
+	t.ids[o] = t.tree.Insert(o, objectBounds(o))
+}
+
+// 木に登録するAABB
+// CheckPointはHitMargin分広げた範囲で当たるので、ObjectsAtで見落とさないようにその分も含める
+func objectBounds(o Object) gmath.Rect {
+	r := o.GetComposit().Bounds()
+	if m, ok := o.(interface{ GetHitMargin() float64 }); ok && m.GetHitMargin() > 0 {
+		d := gmath.Vec{X: m.GetHitMargin(), Y: m.GetHitMargin()}
+		r = gmath.Rect{Min: r.Min.Sub(d), Max: r.Max.Add(d)}
+	}
+	return r
+}
+
+// オブジェクトを削除する
+func (t *ObjectTree) Remove(o Object) {
+	id, found := t.ids[o]
+	if !found {
+		return
+	}
+	t.tree.Remove(id)
+	delete(t.ids, o)
+}
+
+// 登録されているオブジェクトの位置を反映する
+// 各オブジェクトのUpdateの後に呼ぶ
+func (t *ObjectTree) Update() {
+	for o, id := range t.ids {
+		t.tree.Move(id, objectBounds(o))
+	}
+}
+
+// 座標(x, y)にあるオブジェクトを返す
+// maskは対象にする種類のビットで、0なら全てのオブジェクトが対象になる
+// 点や矩形の問い合わせは重なりを調べるものなので、センサーも対象になる
+func (t *ObjectTree) ObjectsAt(x, y float64, mask uint32) []Object {
+	result := []Object{}
+	t.tree.QueryPoint(gmath.Vec{X: x, Y: y}, func(_ int, o Object) bool {
+		if o.GetComposit().Filter.Matches(mask) && o.CheckPoint(x, y) {
+			result = append(result, o)
+		}
+		return true
+	})
+	return result
+}
+
+// 矩形と重なっているオブジェクトを返す
+// ObjectsAtと同じくセンサーも対象になる
+func (t *ObjectTree) ObjectsIn(r gmath.Rect, mask uint32) []Object {
+	area := &collision.Polygon{
+		Vertices: []gmath.Vec{
+			r.Min,
+			{X: r.Max.X, Y: r.Min.Y},
+			r.Max,
+			{X: r.Min.X, Y: r.Max.Y},
+		},
+	}
+
+	result := []Object{}
+	t.tree.QueryRect(r, func(_ int, o Object) bool {
+		if c := o.GetComposit(); c.Filter.Matches(mask) && area.Test(c) {
+			result = append(result, o)
+		}
+		return true
+	})
+	return result
+}
+
+// レイを飛ばして一番近くで当たったオブジェクトを返す
+// センサーはRaycastと同じく無視する
+func (t *ObjectTree) Raycast(origin, dir gmath.Vec, maxDist float64, mask uint32) (Object, collision.RayHit, bool) {
+	candidates := []Object{}
+	t.tree.QueryRay(origin, dir, maxDist, func(_ int, o Object) bool {
+		candidates = append(candidates, o)
+		return true
+	})
+	return Raycast(candidates, origin, dir, maxDist, mask)
+}
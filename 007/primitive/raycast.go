@@ -0,0 +1,55 @@
+package primitive
+
+import (
+	"myproject/collision"
+
+	"github.com/quasilyte/gmath"
+)
+
+// レイを飛ばして一番近くで当たったオブジェクトを返す
+// maxDistより遠いものは無視する。制限しない場合はmath.Inf(1)を渡す
+// maskは対象にする種類のビットで、0なら全てのオブジェクトが対象になる。センサーは無視する
+func Raycast(objects []Object, origin, dir gmath.Vec, maxDist float64, mask uint32) (Object, collision.RayHit, bool) {
+	var result Object
+	var hit collision.RayHit
+	for _, o := range objects {
+		c := o.GetComposit()
+		if !c.Filter.Hits(mask) {
+			continue
+		}
+		h, ok := c.Raycast(origin, dir, maxDist)
+		if ok && (result == nil || h.Distance < hit.Distance) {
+			result = o
+			hit = h
+		}
+	}
+
+	return result, hit, result != nil
+}
+
+// オブジェクトをdeltaだけ動かしたときに最初に当たるオブジェクトを返す
+// 自分自身とセンサー、maskやフィルターで衝突しないオブジェクトは無視する
+func ShapeCast(o Object, delta gmath.Vec, objects []Object, mask uint32) (Object, collision.ShapeHit, bool) {
+	c := o.GetComposit()
+	from := collision.Transform{Pos: o.GetPos()}
+	if t, ok := o.(interface{ GetTransform() collision.Transform }); ok {
+		from = t.GetTransform()
+	}
+
+	var targets []Object
+	var testers []collision.Tester
+	for _, t := range objects {
+		tc := t.GetComposit()
+		if t == o || !tc.Filter.Hits(mask) || !c.Filter.ShouldCollide(tc.Filter) {
+			continue
+		}
+		targets = append(targets, t)
+		testers = append(testers, tc)
+	}
+
+	hit, ok := collision.ShapeCast(c, from, delta, testers)
+	if !ok {
+		return nil, hit, false
+	}
+	return targets[hit.Index], hit, true
+}
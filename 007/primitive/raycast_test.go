@@ -0,0 +1,44 @@
+package primitive
+
+import (
+	"testing"
+
+	"github.com/quasilyte/gmath"
+)
+
+// 自分自身、センサー、maskで外した相手を飛ばして一番近い相手に当たる
+func TestShapeCastSkips(t *testing.T) {
+	mover := NewCircle(0, 0, 10)
+	sensor := NewRect(30, 0, 10, 100, 0)
+	sensor.Filter.Sensor = true
+	masked := NewRect(50, 0, 10, 100, 0)
+	masked.Filter.Category = 2
+	solid := NewRect(70, 0, 10, 100, 0)
+	far := NewRect(90, 0, 10, 100, 0)
+	objects := []Object{far, mover, sensor, masked, solid}
+	for _, o := range objects {
+		o.Update()
+	}
+
+	o, h, ok := ShapeCast(mover, gmath.Vec{X: 100}, objects, 1)
+	if !ok || o != solid {
+		t.Fatalf("ShapeCast hit %v, want the solid wall", o)
+	}
+	// 壁の面x=65に半径10の円が触れるまで55進む
+	if d := h.Fraction - 0.55; d > 1e-6 || d < -1e-6 {
+		t.Errorf("fraction = %v, want 0.55", h.Fraction)
+	}
+
+	// maskが0なら種類で外さない
+	if o, _, ok := ShapeCast(mover, gmath.Vec{X: 100}, objects, 0); !ok || o != masked {
+		t.Errorf("ShapeCast with mask 0 hit %v, want the masked wall", o)
+	}
+
+	// レイも同じくセンサーを無視する
+	if o, _, ok := Raycast(objects, gmath.Vec{}, gmath.Vec{X: 1}, 100, 0); !ok || o != mover {
+		t.Errorf("Raycast hit %v, want the mover it starts in", o)
+	}
+	if o, _, ok := Raycast(objects, gmath.Vec{X: 20}, gmath.Vec{X: 1}, 100, 1); !ok || o != solid {
+		t.Errorf("Raycast hit %v, want the solid wall", o)
+	}
+}
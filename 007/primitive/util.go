@@ -144,12 +144,19 @@ func NewHarfCircle(x, y, r float64) *HarfCircle {
 }
 
 // HarfCircleは特殊な形状なので自前で描画する
+// 縦横で違う拡大率の場合は円弧にならないので衝突判定の範囲を描画する
 func (c *HarfCircle) Draw(screen *ebiten.Image) {
+	s := collision.ScaleOf(c.Scale)
+	if s.X != s.Y || s.X < 0 {
+		c.Base.Draw(screen)
+		return
+	}
+
 	var path vector.Path
 
 	// 半円描画
 	path.MoveTo(float32(c.Pos.X), float32(c.Pos.Y))
-	path.Arc(float32(c.Pos.X), float32(c.Pos.Y), float32(c.Radius), float32(c.Rad)-math.Pi*0.5, float32(c.Rad)+math.Pi*0.5, vector.Clockwise)
+	path.Arc(float32(c.Pos.X), float32(c.Pos.Y), float32(c.Radius*math.Abs(s.X)), float32(c.Rad)-math.Pi*0.5, float32(c.Rad)+math.Pi*0.5, vector.Clockwise)
 	path.Close()
 
 	// 描画用頂点情報作成
@@ -172,7 +179,7 @@ func (c *HarfCircle) Draw(screen *ebiten.Image) {
 	screen.DrawTriangles(vertices, indices, whitePixel, op)
 }
 
-// シンプル円。小さいとタッチ操作しにくいので衝突判定は大きめ
+// シンプル円。小さいとタッチ操作しにくいのでタッチの判定は大きめ
 type SimpleCircle struct {
 	Base
 	Radius float64
@@ -181,7 +188,7 @@ type SimpleCircle struct {
 func NewSimpleCircle(x, y, r float64) *SimpleCircle {
 	c := collision.Circle{
 		Pos:    gmath.Vec{X: x, Y: y},
-		Radius: r + 10,
+		Radius: r,
 	}
 
 	return &SimpleCircle{
@@ -191,8 +198,9 @@ func NewSimpleCircle(x, y, r float64) *SimpleCircle {
 			Composit: collision.Composit{
 				Collisions: []collision.Tester{&c},
 			},
+			HitMargin: 10,
 		},
-		Radius: r + 10,
+		Radius: r,
 	}
 }
 
@@ -202,5 +210,200 @@ func (c *SimpleCircle) Move(fx, fy, tx, ty float64) {
 }
 
 func (c *SimpleCircle) Draw(screen *ebiten.Image) {
-	vector.DrawFilledCircle(screen, float32(c.Pos.X), float32(c.Pos.Y), float32(c.Radius-10), c.FillColor, true)
+	vector.DrawFilledCircle(screen, float32(c.Pos.X), float32(c.Pos.Y), float32(c.Radius), c.FillColor, true)
+}
+
+// リング。外側の円から内側の円を取り除く
+func NewRing(x, y, outer, inner float64) *Base {
+	return &Base{
+		Pos:       gmath.Vec{X: x, Y: y},
+		FillColor: color.RGBA{0x00, 0xff, 0xff, 0xff},
+		Composit: collision.Composit{
+			Collisions: []collision.Tester{
+				&collision.Circle{Pos: gmath.Vec{X: x, Y: y}, Radius: outer},
+				&collision.Circle{Pos: gmath.Vec{X: x, Y: y}, Radius: inner},
+			},
+			Operator: collision.CompositNot,
+		},
+	}
+}
+
+// 中空の枠。外側の矩形から内側の矩形を取り除く
+func NewFrame(x, y, w, h, t, r float64) *Base {
+	outer := NewRect(x, y, w, h, r)
+	inner := NewRect(x, y, w-t*2, h-t*2, r)
+
+	return &Base{
+		Pos:       gmath.Vec{X: x, Y: y},
+		Rad:       gmath.Rad(r),
+		FillColor: color.RGBA{0x00, 0xff, 0xff, 0xff},
+		Composit: collision.Composit{
+			Collisions: []collision.Tester{&outer.Composit, &inner.Composit},
+			Operator:   collision.CompositNot,
+		},
+	}
+}
+
+// 凹型を含む多角形。衝突判定は凸型多角形に分解したもので行い、描画は元の外形で行う
+type ConcavePolygon struct {
+	Base
+	Outline []gmath.Vec // 元の外形
+}
+
+func NewConcavePolygon(x, y, r float64, vs []gmath.Vec) (*ConcavePolygon, error) {
+	c, err := collision.NewConcave(vs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConcavePolygon{
+		Base: Base{
+			Pos:       gmath.Vec{X: x, Y: y},
+			Rad:       gmath.Rad(r),
+			FillColor: color.RGBA{0x00, 0xff, 0xff, 0xff},
+			Composit:  *c,
+		},
+		Outline: vs,
+	}, nil
+}
+
+// 分解した形状の境目が見えないように元の外形で描画する
+func (c *ConcavePolygon) Draw(screen *ebiten.Image) {
+	vs := make([]gmath.Vec, 0, len(c.Outline))
+	s := collision.ScaleOf(c.Scale)
+	for _, v := range c.Outline {
+		vs = append(vs, v.Mul(s).Rotated(c.Rad).Add(c.Pos))
+	}
+	drawPolygons(screen, [][]gmath.Vec{vs}, c.FillColor)
+}
+
+// 多角形の演算で作った、穴を含む輪郭の集合で表す形状
+type RegionPolygon struct {
+	Base
+	Outlines [][]gmath.Vec // 外形は右回り、穴は左回りの輪郭(Posからの相対座標)
+}
+
+// 輪郭の集合から作る形状
+// 輪郭はcollision.ClipやClipRegionsの結果をそのまま使える
+func NewRegionPolygon(x, y, r float64, outlines [][]gmath.Vec) (*RegionPolygon, error) {
+	c, err := collision.NewRegion(outlines)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegionPolygon{
+		Base: Base{
+			Pos:       gmath.Vec{X: x, Y: y},
+			Rad:       gmath.Rad(r),
+			FillColor: color.RGBA{0x00, 0xff, 0xff, 0xff},
+			Composit:  *c,
+		},
+		Outlines: outlines,
+	}, nil
+}
+
+// 2つの多角形を演算した形状
+func NewClippedPolygon(x, y, r float64, a, b []gmath.Vec, op collision.ClipOperation) (*RegionPolygon, error) {
+	outlines, err := collision.Clip(a, b, op)
+	if err != nil {
+		return nil, err
+	}
+	return NewRegionPolygon(x, y, r, outlines)
+}
+
+// 分解した形状の境目が見えないように輪郭で描画する
+// 穴は逆回りなので塗りつぶされない
+func (c *RegionPolygon) Draw(screen *ebiten.Image) {
+	s := collision.ScaleOf(c.Scale)
+	polygons := make([][]gmath.Vec, 0, len(c.Outlines))
+	for _, o := range c.Outlines {
+		vs := make([]gmath.Vec, 0, len(o))
+		for _, v := range o {
+			vs = append(vs, v.Mul(s).Rotated(c.Rad).Add(c.Pos))
+		}
+		polygons = append(polygons, vs)
+	}
+	drawPolygons(screen, polygons, c.FillColor)
+}
+
+// 点集合の凸包から作る凸型多角形
+// 点はグローバル座標で指定する。重心を座標にするので、そこを中心に回転する
+// nが3以上なら頂点をその数まで減らす
+func NewHullPolygon(points []gmath.Vec, n int) (*Base, error) {
+	p, err := collision.NewConvexHull(points, n)
+	if err != nil {
+		return nil, err
+	}
+
+	c := p.Mass(1).Centroid
+	vs := make([]gmath.Vec, 0, len(p.Vertices))
+	for _, v := range p.Vertices {
+		vs = append(vs, v.Sub(c))
+	}
+	return NewPolygon(c.X, c.Y, 0, vs), nil
+}
+
+// カプセル。長さlの線分を半径r分太らせた形
+// 拡大率(Base.Scale)は反映しない
+func NewCapsule(x, y, l, r, rad float64) *Base {
+	c := collision.Capsule{
+		A:      gmath.Vec{X: -l / 2, Y: 0},
+		B:      gmath.Vec{X: l / 2, Y: 0},
+		Radius: r,
+	}
+
+	return &Base{
+		Pos:       gmath.Vec{X: x, Y: y},
+		Rad:       gmath.Rad(rad),
+		FillColor: color.RGBA{0x00, 0xff, 0xff, 0xff},
+		Composit: collision.Composit{
+			Collisions: []collision.Tester{&c},
+		},
+	}
+}
+
+// 楕円
+// 拡大率(Base.Scale)は反映しない。縦横で違う半径はrx/ryで指定する
+func NewEllipse(x, y, rx, ry, rad float64) *Base {
+	e := collision.Ellipse{
+		RadiusX: rx,
+		RadiusY: ry,
+	}
+
+	return &Base{
+		Pos:       gmath.Vec{X: x, Y: y},
+		Rad:       gmath.Rad(rad),
+		FillColor: color.RGBA{0x00, 0xff, 0xff, 0xff},
+		Composit: collision.Composit{
+			Collisions: []collision.Tester{&e},
+		},
+	}
+}
+
+// ダンベル。長さlの棒の両端に半径rの円が付いた形
+// 円は子要素ごとの相対位置で両端に置く
+func NewDumbbell(x, y, l, r, rad float64) *Base {
+	return &Base{
+		Pos:       gmath.Vec{X: x, Y: y},
+		Rad:       gmath.Rad(rad),
+		FillColor: color.RGBA{0x00, 0xff, 0xff, 0xff},
+		Composit: collision.Composit{
+			Collisions: []collision.Tester{
+				&collision.Circle{Radius: r},
+				&collision.Circle{Radius: r},
+				&collision.Polygon{
+					Vertices: []gmath.Vec{
+						{X: -l / 2, Y: -r / 4},
+						{X: l / 2, Y: -r / 4},
+						{X: l / 2, Y: r / 4},
+						{X: -l / 2, Y: r / 4},
+					},
+				},
+			},
+			Locals: []collision.Transform{
+				{Pos: gmath.Vec{X: -l / 2, Y: 0}},
+				{Pos: gmath.Vec{X: l / 2, Y: 0}},
+			},
+		},
+	}
 }
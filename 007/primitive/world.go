@@ -0,0 +1,185 @@
+package primitive
+
+import "slices"
+
+// 衝突の始まり・継続・終わりに呼ばれる関数
+// selfは登録したオブジェクト、otherは相手のオブジェクト。nilの関数は呼ばれない
+type Callbacks struct {
+	OnEnter func(self, other Object) // 重なり始めたフレーム
+	OnStay  func(self, other Object) // 重なり続けている間の毎フレーム
+	OnExit  func(self, other Object) // 離れたフレーム
+}
+
+// 通知する変化の種類
+type collisionEvent int
+
+const (
+	eventEnter collisionEvent = iota
+	eventStay
+	eventExit
+)
+
+// 変化の種類に対応する関数
+func (cb Callbacks) get(e collisionEvent) func(self, other Object) {
+	switch e {
+	case eventEnter:
+		return cb.OnEnter
+	case eventStay:
+		return cb.OnStay
+	}
+	return cb.OnExit
+}
+
+// 重なっているオブジェクトのペア
+// 先に登録したオブジェクトをaにする
+type objectPair struct {
+	a, b Object
+}
+
+// オブジェクト同士の重なりをフレームをまたいで追跡して、変化を通知する
+// 判定の絞り込みはBroadphaseとオブジェクトの絞り込み条件に従う
+type World struct {
+	broadphase *Broadphase
+	ids        map[Object]int       // 登録順
+	callbacks  map[Object]Callbacks // オブジェクトごとの通知先
+	nextID     int
+
+	touching map[objectPair]struct{} // 前のフレームで重なっていたペア
+	order    []objectPair            // touchingを見つけた順番
+
+	stepping bool     // Stepで通知している途中
+	removed  []Object // Stepの通知中に削除されたオブジェクト
+}
+
+// cellSizeはBroadphaseの空間ハッシュのセルの大きさ
+func NewWorld(cellSize float64) *World {
+	return &World{
+		broadphase: NewBroadphase(cellSize),
+		ids:        map[Object]int{},
+		callbacks:  map[Object]Callbacks{},
+		touching:   map[objectPair]struct{}{},
+	}
+}
+
+// オブジェクトを登録する
+func (w *World) Add(o Object, cb Callbacks) {
+	// 通知中に削除して登録し直した場合は削除を取り消す
+	if i := slices.Index(w.removed, o); i >= 0 {
+		w.removed = slices.Delete(w.removed, i, i+1)
+	}
+	if _, found := w.ids[o]; found {
+		w.callbacks[o] = cb
+		return
+	}
+	w.ids[o] = w.nextID
+	w.nextID++
+	w.callbacks[o] = cb
+	w.broadphase.Add(o)
+}
+
+// オブジェクトを削除する
+// 重なっていた相手とは離れたことにして通知する
+// 通知の関数の中で呼んだ場合は、Stepの通知が全て終わってから削除する
+func (w *World) Remove(o Object) {
+	if _, found := w.ids[o]; !found {
+		return
+	}
+	if w.stepping {
+		if !slices.Contains(w.removed, o) {
+			w.removed = append(w.removed, o)
+		}
+		return
+	}
+
+	// 通知の中で他のオブジェクトを削除しても壊れないように、先にペアを外しておく
+	var exits []objectPair
+	order := w.order[:0:0]
+	for _, p := range w.order {
+		if p.a == o || p.b == o {
+			exits = append(exits, p)
+			delete(w.touching, p)
+			continue
+		}
+		order = append(order, p)
+	}
+	w.order = order
+	w.broadphase.Remove(o)
+	delete(w.ids, o)
+
+	for _, p := range exits {
+		w.notify(p, eventExit)
+	}
+
+	// 通知の中で登録し直した場合は新しい通知先を残す
+	if _, found := w.ids[o]; !found {
+		delete(w.callbacks, o)
+	}
+}
+
+// 重なりを調べて通知する
+// 各オブジェクトのUpdateの後に1フレーム1回呼ぶ
+// 通知の中で削除されたオブジェクトは、このフレームの通知が終わってから離れたことを通知する
+func (w *World) Step() {
+	w.broadphase.Update()
+
+	touching := map[objectPair]struct{}{}
+	var order []objectPair
+	w.broadphase.Collisions(func(o1, o2 Object) {
+		p := w.pair(o1, o2)
+		if _, found := touching[p]; found {
+			return
+		}
+		touching[p] = struct{}{}
+		order = append(order, p)
+	})
+
+	w.stepping = true
+	for _, p := range order {
+		if _, found := w.touching[p]; found {
+			w.notify(p, eventStay)
+		} else {
+			w.notify(p, eventEnter)
+		}
+	}
+	for _, p := range w.order {
+		if _, found := touching[p]; !found {
+			w.notify(p, eventExit)
+		}
+	}
+
+	w.touching = touching
+	w.order = order
+	w.stepping = false
+
+	removed := w.removed
+	w.removed = nil
+	for _, o := range removed {
+		w.Remove(o)
+	}
+}
+
+// 重なっているかどうか
+func (w *World) Touching(o1, o2 Object) bool {
+	_, found := w.touching[w.pair(o1, o2)]
+	return found
+}
+
+// 登録順に並べたペア
+func (w *World) pair(o1, o2 Object) objectPair {
+	if w.ids[o2] < w.ids[o1] {
+		o1, o2 = o2, o1
+	}
+	return objectPair{o1, o2}
+}
+
+// ペアの両方のオブジェクトに通知する
+// 片方への通知の中で相手が削除されても、相手にもこの通知を届ける
+func (w *World) notify(p objectPair, e collisionEvent) {
+	fa, fb := w.callbacks[p.a].get(e), w.callbacks[p.b].get(e)
+	if fa != nil {
+		fa(p.a, p.b)
+	}
+	if fb != nil {
+		fb(p.b, p.a)
+	}
+}
@@ -0,0 +1,123 @@
+package primitive
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// 通知を"自分 種類 相手"の形で記録する
+type eventLog struct {
+	names  map[Object]string
+	events []string
+}
+
+func (l *eventLog) callbacks(hook func(e string, self, other Object)) Callbacks {
+	rec := func(e string) func(self, other Object) {
+		return func(self, other Object) {
+			l.events = append(l.events, fmt.Sprintf("%s %s %s", l.names[self], e, l.names[other]))
+			if hook != nil {
+				hook(e, self, other)
+			}
+		}
+	}
+	return Callbacks{OnEnter: rec("enter"), OnStay: rec("stay"), OnExit: rec("exit")}
+}
+
+// 1フレーム分進めて、そのフレームの通知を返す
+func (l *eventLog) step(w *World, objects ...*Base) []string {
+	for _, o := range objects {
+		o.Update()
+	}
+	l.events = nil
+	w.Step()
+	return l.events
+}
+
+func TestWorldEvents(t *testing.T) {
+	const apart, touching = 200.0, 115.0
+	tests := []struct {
+		name  string
+		steps []float64 // フレームごとのbのX座標
+		want  [][]string
+	}{
+		{
+			name:  "enter, stay and exit",
+			steps: []float64{apart, touching, touching, apart, apart},
+			want: [][]string{
+				nil,
+				{"a enter b", "b enter a"},
+				{"a stay b", "b stay a"},
+				{"a exit b", "b exit a"},
+				nil,
+			},
+		},
+		{
+			name:  "enter again after exit",
+			steps: []float64{touching, apart, touching},
+			want: [][]string{
+				{"a enter b", "b enter a"},
+				{"a exit b", "b exit a"},
+				{"a enter b", "b enter a"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		a := NewCircle(100, 100, 10)
+		b := NewCircle(apart, 100, 10)
+		log := &eventLog{names: map[Object]string{a: "a", b: "b"}}
+		w := NewWorld(64)
+		w.Add(a, log.callbacks(nil))
+		w.Add(b, log.callbacks(nil))
+
+		for i, x := range tt.steps {
+			b.Pos.X = x
+			if got := log.step(w, a, b); !slices.Equal(got, tt.want[i]) {
+				t.Errorf("%s: frame %d: events = %q, want %q", tt.name, i, got, tt.want[i])
+			}
+			if got, want := w.Touching(a, b), x == touching; got != want {
+				t.Errorf("%s: frame %d: Touching = %v, want %v", tt.name, i, got, want)
+			}
+		}
+	}
+}
+
+// 通知の中で削除しても、離れた通知はStepの最後に1回だけ届き、その後は何も届かない
+func TestWorldRemoveInCallback(t *testing.T) {
+	for _, event := range []string{"enter", "stay"} {
+		a := NewCircle(100, 100, 10)
+		b := NewCircle(115, 100, 10)
+		log := &eventLog{names: map[Object]string{a: "a", b: "b"}}
+		w := NewWorld(64)
+		remove := func(e string, self, other Object) {
+			if e == event && self == a {
+				w.Remove(a)
+			}
+		}
+		w.Add(a, log.callbacks(remove))
+		w.Add(b, log.callbacks(nil))
+
+		var got []string
+		for i := 0; i < 4; i++ {
+			got = append(got, log.step(w, a, b)...)
+		}
+
+		want := []string{"a enter b", "b enter a", "a exit b", "b exit a"}
+		if event == "stay" {
+			want = []string{"a enter b", "b enter a", "a stay b", "b stay a", "a exit b", "b exit a"}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("remove in %s: events = %q, want %q", event, got, want)
+		}
+		if w.Touching(a, b) {
+			t.Errorf("remove in %s: removed object is still touching", event)
+		}
+
+		// 登録し直すと、重なっていれば改めて重なり始めたことになる
+		w.Add(a, log.callbacks(nil))
+		if got, want := log.step(w, a, b), []string{"b enter a", "a enter b"}; !slices.Equal(got, want) {
+			t.Errorf("remove in %s: events after Add = %q, want %q", event, got, want)
+		}
+	}
+}